@@ -0,0 +1,217 @@
+package rediss
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// BreakerState 熔断器的状态
+type BreakerState int32
+
+const (
+	BreakerClosed   BreakerState = iota // 关闭, 正常放行所有请求
+	BreakerOpen                         // 打开, 所有请求直接返回ErrCircuitOpen
+	BreakerHalfOpen                     // 半开, 只放行少量试探请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	defaultBreakerWindow       = 10 * time.Second
+	defaultBreakerMinRequests  = 10
+	defaultBreakerOpenTimeout  = 5 * time.Second
+	defaultBreakerHalfOpenReqs = 1
+)
+
+// BreakerConfig 熔断器配置
+// 只有网络错误(拨号失败、连接断开、读写超时等)会被视为失败, 服务器返回的错误回复说明服务器仍然可用, 不会触发熔断
+type BreakerConfig struct {
+	ConsecutiveFailures int           // 连续失败达到该次数时熔断, 为0时不启用
+	FailureRate         float64       // 统计窗口内的失败率达到该值时熔断, 取值范围(0, 1], 为0时不启用
+	MinRequests         int           // 计算失败率所需的最少请求数, 默认为10
+	Window              time.Duration // 统计失败率的时间窗口, 默认为10秒
+	OpenTimeout         time.Duration // 熔断后经过该时长进入半开状态, 默认为5秒
+	HalfOpenRequests    int           // 半开状态下允许的试探请求数, 全部成功后关闭熔断器, 默认为1
+
+	OnStateChange func(from, to BreakerState) // 状态变化时调用, 调用时不持有熔断器的锁, 在BreakerHook.BreakerStateChanged之前调用
+}
+
+type circuitBreaker struct {
+	mu     sync.Mutex
+	config BreakerConfig
+	hooks  *hookChain // 状态变化时通知的Hook, 与客户端共享
+	state  BreakerState
+
+	consecutive int       // 连续失败的次数
+	windowStart time.Time // 当前统计窗口的开始时间
+	requests    int       // 当前统计窗口内的请求数
+	failures    int       // 当前统计窗口内的失败数
+
+	openedAt  time.Time // 最近一次熔断的时间
+	trials    int       // 半开状态下已经放行的试探请求数
+	successes int       // 半开状态下成功的试探请求数
+}
+
+func newCircuitBreaker(config BreakerConfig) *circuitBreaker {
+	if config.MinRequests <= 0 {
+		config.MinRequests = defaultBreakerMinRequests
+	}
+	if config.Window <= 0 {
+		config.Window = defaultBreakerWindow
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaultBreakerOpenTimeout
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = defaultBreakerHalfOpenReqs
+	}
+	return &circuitBreaker{
+		config:      config,
+		windowStart: time.Now(),
+	}
+}
+
+func (cb *circuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// 判断是否放行请求, 放行时需要在请求结束后调用done, trial表示请求是否为半开状态下放行的试探请求
+func (cb *circuitBreaker) allow() (trial bool, err error) {
+	cb.mu.Lock()
+	from := cb.state
+	if cb.state == BreakerOpen && time.Since(cb.openedAt) >= cb.config.OpenTimeout {
+		cb.setStateLocked(BreakerHalfOpen)
+	}
+	switch cb.state {
+	case BreakerOpen:
+		err = ErrCircuitOpen
+	case BreakerHalfOpen:
+		if cb.trials >= cb.config.HalfOpenRequests {
+			err = ErrCircuitOpen
+		} else {
+			cb.trials++
+			trial = true
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+	cb.notify(from, to)
+	return
+}
+
+// 记录请求的结果, trial为allow返回的值
+// 半开状态下只统计试探请求, 关闭状态时放行、在半开状态下才结束的请求不能说明服务器已经恢复
+func (cb *circuitBreaker) done(trial bool, err error) {
+	failed := isTransportError(err)
+
+	cb.mu.Lock()
+	from := cb.state
+	switch cb.state {
+	case BreakerClosed:
+		now := time.Now()
+		if now.Sub(cb.windowStart) > cb.config.Window {
+			cb.windowStart, cb.requests, cb.failures = now, 0, 0
+		}
+		cb.requests++
+		if !failed {
+			cb.consecutive = 0
+			break
+		}
+		cb.failures++
+		cb.consecutive++
+		if cb.shouldTripLocked() {
+			cb.setStateLocked(BreakerOpen)
+		}
+	case BreakerHalfOpen:
+		if !trial {
+			break
+		}
+		if failed {
+			cb.setStateLocked(BreakerOpen)
+			break
+		}
+		cb.successes++
+		if cb.successes >= cb.config.HalfOpenRequests {
+			cb.setStateLocked(BreakerClosed)
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+	cb.notify(from, to)
+}
+
+func (cb *circuitBreaker) shouldTripLocked() bool {
+	if n := cb.config.ConsecutiveFailures; n > 0 && cb.consecutive >= n {
+		return true
+	}
+	if rate := cb.config.FailureRate; rate > 0 && cb.requests >= cb.config.MinRequests {
+		return float64(cb.failures)/float64(cb.requests) >= rate
+	}
+	return false
+}
+
+// 切换状态并重置相应的计数
+// 调用方需要持有锁
+func (cb *circuitBreaker) setStateLocked(state BreakerState) {
+	cb.state = state
+	cb.trials, cb.successes = 0, 0
+	switch state {
+	case BreakerOpen:
+		cb.openedAt = time.Now()
+	case BreakerClosed:
+		cb.consecutive = 0
+		cb.windowStart, cb.requests, cb.failures = time.Now(), 0, 0
+	}
+}
+
+func (cb *circuitBreaker) notify(from, to BreakerState) {
+	if from == to {
+		return
+	}
+	if cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(from, to)
+	}
+	if cb.hooks == nil {
+		return
+	}
+	for _, h := range cb.hooks.get() {
+		if bh, ok := h.(BreakerHook); ok {
+			bh.BreakerStateChanged(from, to)
+		}
+	}
+}
+
+// 判断是否为网络错误, 服务器返回的错误回复不属于网络错误
+func isTransportError(err error) bool {
+	switch err {
+	case nil:
+		return false
+	case io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// BreakerState 返回熔断器当前的状态, 没有开启熔断器时始终返回BreakerClosed
+func (c *Client) BreakerState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+	return c.breaker.State()
+}
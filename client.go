@@ -1,7 +1,11 @@
 package rediss
 
 import (
-	"net"
+	"context"
+	"crypto/tls"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pyihe/go-pkg/serialize"
@@ -9,98 +13,475 @@ import (
 	"github.com/pyihe/rediss/pool"
 )
 
+const (
+	// 通过CLIENT SETINFO上报的客户端库名称
+	libName = "rediss"
+
+	// 阻塞命令的读超时在命令本身的超时时间上增加的余量, 以容纳网络延迟
+	blockingReadMargin = time.Second
+)
+
 type Client struct {
+	network      string          // 网络类型, tcp或者unix
 	address      string          // redis地址
 	username     string          // 用户名
 	password     string          // 密码
-	database     int32           // db索引
+	clientName   string          // 连接名称, 通过CLIENT SETNAME设置
+	database     *int32          // db索引, 通过atomic读写, 每个视图拥有独立的副本, 避免With拷贝时与Select并发读写
 	writeTimeout time.Duration   // 每次发送请求的超时时间
 	readTimeout  time.Duration   // 每次读取回复的超时时间
 	codec        serialize.Codec // 序列化
+	sentinel     bool            // 是否连接的是哨兵, 哨兵不支持SELECT命令
+	dialTimeout  time.Duration   // 拨号超时时间
+	tlsConfig    *tls.Config     // TLS配置, 为nil时不使用TLS
 
 	pool       *pool.Pool   // 连接池
 	poolConfig *pool.Config // 连接池配置
+
+	blocking *blockingPool   // 阻塞命令专用的连接池
+	breaker  *circuitBreaker // 熔断器, 为nil时不开启
+	hooks    *hookChain      // 命令以及拨号的Hook
+	commands *commandCache   // 命令元数据缓存
+	server   *serverInfo     // 服务器版本以及模块信息
+	creds    *credentials    // 凭证提供者以及凭证版本
+	pinned   *pinnedConn     // 固定的连接, 只能通过Conn设置
+
+	retryPolicy *RetryPolicy    // 命令的重试策略, 为nil时不重试
+	noRetry     bool            // 是否禁止重试, 只能通过With设置
+	ctx         context.Context // 调用的上下文, 只能通过With设置, 用于传递链路追踪信息以及取消等待连接
+	retryUnsafe bool            // 是否允许重试非幂等的命令, 只能通过With设置
+}
+
+// 返回当前选择的数据库
+func (c *Client) db() int32 {
+	return atomic.LoadInt32(c.database)
+}
+
+func newDatabase(db int32) *int32 {
+	return &db
 }
 
-func New(opts ...Option) *Client {
+// 返回调用的上下文, 没有通过With设置时返回context.Background()
+func (c *Client) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// 阻塞命令专用的连接池, 第一次执行阻塞命令时创建
+type blockingPool struct {
+	mu     sync.Mutex
+	pool   *pool.Pool
+	size   int  // 最大连接数, 默认与连接池相同
+	closed bool // 客户端是否已经关闭
+}
+
+// New 创建客户端, 配置错误时返回错误
+// 客户端创建时不会等待连接建立, 即使Redis暂时不可用也能创建成功, 连接池将在后台预热连接
+func New(opts ...Option) (*Client, error) {
+	retryPolicy := DefaultRetryPolicy
 	c := &Client{
-		address:    "127.0.0.1:6379", // 默认连接本机redis
-		password:   "",               // 默认无密码
-		database:   0,                // 默认选择索引为0的数据库
-		poolConfig: &pool.Config{},
+		network:     "tcp",
+		address:     "127.0.0.1:6379", // 默认连接本机redis
+		password:    "",               // 默认无密码
+		database:    new(int32),       // 默认选择索引为0的数据库
+		retryPolicy: &retryPolicy,     // 默认使用DefaultRetryPolicy
+		poolConfig:  &pool.Config{},
+		blocking:    &blockingPool{},
+		hooks:       &hookChain{},
+		commands:    &commandCache{},
+		server:      &serverInfo{},
+		creds:       &credentials{},
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
-	c.poolConfig.Dialer = func() (net.Conn, error) {
-		return net.Dial("tcp", c.address)
+	if err := checkDatabase(c.db()); err != nil {
+		return nil, err
 	}
-	c.pool = pool.New(c.poolConfig)
-
-	assertDatabase(c.database)
-	return c
+	c.poolConfig.Dialer = c.dial
+	// 握手完成后再调用用户设置的 OnConnect
+	onConnect := c.poolConfig.OnConnect
+	c.poolConfig.OnConnect = func(conn *pool.RedisConn) error {
+		if err := c.initConn(conn); err != nil {
+			return err
+		}
+		if onConnect != nil {
+			return onConnect(conn)
+		}
+		return nil
+	}
+	c.poolConfig.KeepAlive = c.keepAlive
+	if c.blocking.size <= 0 {
+		c.blocking.size = c.poolConfig.MaxConnSize
+	}
+	p, err := pool.New(c.poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	c.pool = p
+	return c, nil
 }
 
+// Close 关闭客户端, 通过With创建的视图共享同一个客户端, 关闭任意一个即关闭所有;
+// 从Conn创建的视图(如Conn.DB、Conn.With)只归还固定的连接, 与Conn.Close相同, 不会关闭客户端
 func (c *Client) Close() {
+	if c.pinned != nil {
+		_ = c.pinned.close()
+		return
+	}
 	c.pool.Close()
+
+	c.blocking.mu.Lock()
+	c.blocking.closed = true
+	if c.blocking.pool != nil {
+		c.blocking.pool.Close()
+	}
+	c.blocking.mu.Unlock()
+}
+
+// With 返回一个应用了调用选项的客户端视图, 视图与原客户端共享连接池等所有资源, 只是使用不同的超时、数据库以及重试设置,
+// 创建视图的开销只是一次浅拷贝, 可以为每个请求单独创建, 如:
+// client.With(CallTimeout(50*time.Millisecond), NoRetry()).Get("key")
+func (c *Client) With(opts ...CallOption) *Client {
+	view := *c
+	// Conn的视图共享固定的连接的数据库, 以便跟随在连接上直接发送的SELECT
+	if c.pinned == nil {
+		view.database = newDatabase(c.db())
+	}
+	for _, opt := range opts {
+		opt(&view)
+	}
+	return &view
+}
+
+// DB 返回在数据库db上执行命令的视图, 与客户端共享连接池, 等同于c.With(DB(db))
+// 每个连接记录当前选择的数据库, 只有连接的数据库与视图不一致时才会发送SELECT
+func (c *Client) DB(db int) *Client {
+	return c.With(DB(db))
+}
+
+// PoolStats 返回连接池的统计信息
+func (c *Client) PoolStats() *pool.Stats {
+	return c.pool.Stats()
+}
+
+// BlockingPoolStats 返回阻塞命令专用连接池的统计信息, 如果还没有执行过阻塞命令则返回nil
+func (c *Client) BlockingPoolStats() *pool.Stats {
+	c.blocking.mu.Lock()
+	defer c.blocking.mu.Unlock()
+	if c.blocking.pool == nil {
+		return nil
+	}
+	return c.blocking.pool.Stats()
+}
+
+// 获取阻塞命令专用的连接池, 该连接池与普通连接池使用相同的配置, 只是大小单独设置,
+// 使得长时间阻塞的命令不会占用普通命令的连接
+func (c *Client) getBlockingPool() (*pool.Pool, error) {
+	c.blocking.mu.Lock()
+	defer c.blocking.mu.Unlock()
+	if c.blocking.closed {
+		return nil, pool.ErrAlreadyClosedPool
+	}
+	if c.blocking.pool != nil {
+		return c.blocking.pool, nil
+	}
+	cfg := *c.poolConfig
+	cfg.MaxConnSize = c.blocking.size
+	cfg.MinConnSize = 1
+	cfg.Adaptive = false
+	p, err := pool.New(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.blocking.pool = p
+	return p, nil
 }
 
 func (c *Client) DoCommand(cmds ...interface{}) (*Reply, error) {
 	return c.sendCommand(args.Command(cmds...))
 }
 
-func (c *Client) sendCommandWithoutTimeout(cmd []byte) (result *Reply, err error) {
-	conn, err := c.pool.Get(c.checkConn)
+// DoPipeline 将多个命令一次性发送给服务器后再依次读取回复, 多个命令只需要一次网络往返
+// 返回的回复与命令一一对应, 服务器对单个命令返回的错误保存在对应回复的Err中, 回复为nil的命令对应的位置为nil;
+// 只有在发生网络错误时才返回错误, 管道不会被重试
+func (c *Client) DoPipeline(cmds ...[]interface{}) ([]*Reply, error) {
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	encoded := make([][]byte, len(cmds))
+	for i := range cmds {
+		encoded[i] = args.Command(cmds[i]...)
+	}
+	return c.processPipelineHook(encoded, func(hcs []*Cmd) ([]*Reply, error) {
+		for _, cmd := range encoded {
+			if err := c.checkSupported(cmd); err != nil {
+				return nil, err
+			}
+		}
+		return c.sendPipeline(encoded, hcs)
+	})
+}
+
+func (c *Client) sendPipeline(cmds [][]byte, hcs []*Cmd) (replies []*Reply, err error) {
+	if c.breaker != nil {
+		var trial bool
+		if trial, err = c.breaker.allow(); err != nil {
+			return nil, err
+		}
+		defer func() {
+			c.breaker.done(trial, err)
+		}()
+	}
+
+	conn, err := c.getConn(c.pool)
 	if err != nil {
 		return nil, err
 	}
-	if err = writeConn(conn, cmd, 0); err != nil {
-		return
+	defer c.putConn(c.pool, conn)
+	for _, hc := range hcs {
+		hc.ConnID = conn.ID()
 	}
-	result, err = readConn(conn, 0)
-	c.pool.Put(conn)
-	return
+
+	if err = c.prepareConn(conn); err != nil {
+		return nil, err
+	}
+
+	size := 0
+	for _, cmd := range cmds {
+		size += len(cmd)
+	}
+	buf := make([]byte, 0, size)
+	for _, cmd := range cmds {
+		buf = append(buf, cmd...)
+	}
+	if err = writeConn(conn, buf, c.writeTimeout); err != nil {
+		return nil, err
+	}
+
+	replies = make([]*Reply, len(cmds))
+	for i := range cmds {
+		reply, rerr := readConn(conn, c.readTimeout)
+		if conn.Err() != nil {
+			return replies[:i], rerr
+		}
+		if rerr == nil {
+			c.trackSelect(conn, cmds[i], reply)
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// 发送命令并且不设置读写超时, 用于可能长时间阻塞的命令
+func (c *Client) sendCommandWithoutTimeout(cmd []byte) (*Reply, error) {
+	return c.send(cmd, 0, 0)
+}
+
+func (c *Client) sendCommand(cmd []byte) (*Reply, error) {
+	return c.send(cmd, c.writeTimeout, c.readTimeout)
+}
+
+// 通过阻塞命令专用的连接池发送命令, timeout为命令本身的阻塞时长, 为0时表示永久阻塞
+func (c *Client) sendBlockingCommand(cmd []byte, timeout time.Duration) (*Reply, error) {
+	p, err := c.getBlockingPool()
+	if err != nil {
+		return nil, err
+	}
+	return c.sendWithPool(p, cmd, c.writeTimeout, blockingReadTimeout(timeout))
+}
+
+func (c *Client) send(cmd []byte, writeTimeout, readTimeout time.Duration) (*Reply, error) {
+	return c.sendWithPool(c.pool, cmd, writeTimeout, readTimeout)
+}
+
+// 执行Hook并按照重试策略发送命令
+func (c *Client) sendWithPool(p *pool.Pool, cmd []byte, writeTimeout, readTimeout time.Duration) (*Reply, error) {
+	return c.processHook(cmd, func(hc *Cmd) (*Reply, error) {
+		if err := c.checkSupported(cmd); err != nil {
+			return nil, err
+		}
+		return c.sendWithRetry(p, cmd, writeTimeout, readTimeout, hc)
+	})
+}
+
+func (c *Client) sendWithRetry(p *pool.Pool, cmd []byte, writeTimeout, readTimeout time.Duration, hc *Cmd) (*Reply, error) {
+	return c.withRetry(cmd, func() (*Reply, error) {
+		if c.breaker == nil {
+			return c.sendOnce(p, cmd, writeTimeout, readTimeout, hc)
+		}
+		trial, err := c.breaker.allow()
+		if err != nil {
+			return nil, err
+		}
+		reply, err := c.sendOnce(p, cmd, writeTimeout, readTimeout, hc)
+		c.breaker.done(trial, err)
+		return reply, err
+	})
 }
 
-func (c *Client) sendCommand(cmd []byte) (result *Reply, err error) {
-	conn, err := c.pool.Get(c.checkConn)
+// 无论成功与否都将连接归还给连接池, 发生网络错误或者协议错误的连接已经被标记, 将由连接池关闭并替换
+func (c *Client) sendOnce(p *pool.Pool, cmd []byte, writeTimeout, readTimeout time.Duration, hc *Cmd) (result *Reply, err error) {
+	conn, err := c.getConn(p)
 	if err != nil {
 		return nil, err
 	}
-	if err = writeConn(conn, cmd, c.writeTimeout); err != nil {
+	defer c.putConn(p, conn)
+	if hc != nil {
+		hc.ConnID = conn.ID()
+	}
+
+	if err = c.prepareConn(conn); err != nil {
 		return
 	}
-	result, err = readConn(conn, c.readTimeout)
-	c.pool.Put(conn)
+
+	if err = writeConn(conn, cmd, writeTimeout); err != nil {
+		return
+	}
+	result, err = readConn(conn, readTimeout)
+	if isNoAuthError(err) && conn.Err() == nil && (c.creds.provider != nil || len(c.password) > 0) {
+		result, err = c.resendAfterAuth(conn, cmd, writeTimeout, readTimeout)
+	}
+	if err == nil {
+		c.trackSelect(conn, cmd, result)
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "READONLY") {
+		// 故障转移后连接到了副本, 丢弃连接以便重新拨号连接到新的主节点
+		conn.MarkBroken(err)
+	}
 	return
 }
 
-func (c *Client) checkConn(conn *pool.RedisConn) error {
-	err := writeConn(conn, args.Command("PING"), 0)
+// 连接建立后的握手, 每个连接只执行一次:
+// 1. HELLO 2 [AUTH username password] [SETNAME name], 服务器不支持HELLO(v6.0.0之前)时退化为AUTH和CLIENT SETNAME
+// 2. SELECT db, 哨兵不支持SELECT, 默认数据库为0时也不需要发送
+// 3. CLIENT SETINFO LIB-NAME, v7.2.0之前的服务器不支持该命令, 所以忽略其错误
+func (c *Client) initConn(conn *pool.RedisConn) (err error) {
+	username, password, version, err := c.credentials()
+	if err != nil {
+		return err
+	}
+	err = c.hello(conn, username, password)
+	if isAuthError(err) && conn.Err() == nil && c.creds.provider != nil {
+		// 凭证可能在获取之后被轮换, 重新获取后再试一次
+		if username, password, version, err = c.credentials(); err != nil {
+			return err
+		}
+		err = c.hello(conn, username, password)
+	}
 	if err != nil {
 		return err
 	}
-	if _, err = readConn(conn, 0); err != nil {
+	conn.SetAuthVersion(version)
+	db := c.db()
+	if !c.sentinel && db != 0 {
+		if _, err = c.execConn(conn, args.Command("SELECT", db)); err != nil {
+			return err
+		}
+	}
+	conn.SetDB(int(db))
+	if _, err = c.execConn(conn, args.Command("CLIENT", "SETINFO", "LIB-NAME", libName)); err != nil && conn.Err() != nil {
 		return err
 	}
-	if len(c.password) > 0 {
-		var cmd []byte
-		if len(c.username) > 0 {
-			cmd = args.Command("AUTH", c.username, c.password)
-		} else {
-			cmd = args.Command("AUTH", c.password)
+	return nil
+}
+
+func (c *Client) hello(conn *pool.RedisConn, username, password string) error {
+	cmd := args.Get()
+	defer args.Put(cmd)
+
+	cmd.Append("HELLO", "2")
+	if len(password) > 0 {
+		user := username
+		if len(user) == 0 {
+			user = "default"
 		}
-		if err = writeConn(conn, cmd, 0); err != nil {
+		cmd.Append("AUTH", user, password)
+	}
+	if len(c.clientName) > 0 {
+		cmd.Append("SETNAME", c.clientName)
+	}
+
+	reply, err := c.execConn(conn, cmd.Bytes())
+	switch {
+	case err == nil:
+		c.parseHello(reply)
+	case conn.Err() == nil && isUnknownCommand(err):
+		// v6.0.0之前的服务器不支持HELLO
+		if err = c.auth(conn, username, password); err != nil {
 			return err
 		}
-		if _, err = readConn(conn, 0); err != nil {
+		if len(c.clientName) > 0 {
+			if _, err = c.execConn(conn, args.Command("CLIENT", "SETNAME", c.clientName)); err != nil {
+				return err
+			}
+		}
+		if err = c.detectVersion(conn); err != nil {
 			return err
 		}
-	}
-	if err = writeConn(conn, args.Command("SELECT", c.database), 0); err != nil {
+	default:
 		return err
 	}
-	_, err = readConn(conn, 0)
+	conn.SetProtocol(2)
+	conn.SetName(c.clientName)
+	return nil
+}
+
+// 连接闲置时的心跳
+func (c *Client) keepAlive(conn *pool.RedisConn) error {
+	_, err := c.execConn(conn, args.Command("PING"))
 	return err
 }
+
+// 获取执行命令的连接, 通过Conn创建的视图始终使用固定的连接
+func (c *Client) getConn(p *pool.Pool) (*pool.RedisConn, error) {
+	if c.pinned != nil {
+		return c.pinned.get()
+	}
+	ctx := c.context()
+	if c.noRetry {
+		ctx = pool.WithDialRetry(ctx, 0)
+	}
+	return p.Get(ctx, nil)
+}
+
+// 归还执行命令的连接, 固定的连接在Conn.Close时才归还
+func (c *Client) putConn(p *pool.Pool, conn *pool.RedisConn) {
+	if c.pinned == nil {
+		p.Put(conn)
+	}
+}
+
+// 使用连接前同步连接的会话状态: 凭证轮换后重新认证, 数据库不一致时切换数据库
+func (c *Client) prepareConn(conn *pool.RedisConn) error {
+	if err := c.reauth(conn); err != nil {
+		return err
+	}
+	return c.switchDB(conn)
+}
+
+func (c *Client) switchDB(conn *pool.RedisConn) error {
+	db := c.db()
+	if c.sentinel || conn.DB() == int(db) {
+		return nil
+	}
+	if err := checkDatabase(db); err != nil {
+		return err
+	}
+	if _, err := c.execConn(conn, args.Command("SELECT", db)); err != nil {
+		return err
+	}
+	conn.SetDB(int(db))
+	return nil
+}
+
+// 在指定的连接上执行命令
+func (c *Client) execConn(conn *pool.RedisConn, cmd []byte) (*Reply, error) {
+	if err := writeConn(conn, cmd, c.writeTimeout); err != nil {
+		return nil, err
+	}
+	return readConn(conn, c.readTimeout)
+}
@@ -0,0 +1,107 @@
+package rediss
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/model/command"
+)
+
+// 命令元数据缓存, 首次使用时通过COMMAND命令加载
+type commandCache struct {
+	mu    sync.RWMutex
+	infos map[string]*command.Info // key为小写的命令名称
+}
+
+func (cc *commandCache) get(name string) (info *command.Info, loaded bool) {
+	cc.mu.RLock()
+	if cc.infos != nil {
+		info, loaded = cc.infos[name], true
+	}
+	cc.mu.RUnlock()
+	return
+}
+
+func (cc *commandCache) set(infos []*command.Info) {
+	m := make(map[string]*command.Info, len(infos))
+	for _, info := range infos {
+		if info != nil {
+			m[info.Name] = info
+		}
+	}
+	cc.mu.Lock()
+	cc.infos = m
+	cc.mu.Unlock()
+}
+
+// RefreshCommands 重新加载命令元数据, 通常在服务器升级或者加载模块后调用
+func (c *Client) RefreshCommands() error {
+	infos, err := c.Command()
+	if err != nil {
+		return err
+	}
+	c.commands.set(infos)
+	return nil
+}
+
+// LookupCommand 根据完整的命令参数查找命令的元数据, 对于包含子命令的命令(如: CONFIG GET), 返回子命令的元数据
+// 命令元数据在第一次调用时从服务器加载并缓存
+func (c *Client) LookupCommand(cmds ...interface{}) (*command.Info, error) {
+	return c.lookupCommand(toArgv(cmds...))
+}
+
+// CommandKeys 返回完整命令参数中的key, 可用于DoCommand执行的任意命令
+// 对于key位置固定的命令直接根据缓存的元数据计算, 否则通过COMMAND GETKEYS向服务器查询
+func (c *Client) CommandKeys(cmds ...interface{}) ([]string, error) {
+	argv := toArgv(cmds...)
+	info, err := c.lookupCommand(argv)
+	if err != nil {
+		return nil, err
+	}
+	if keys, ok := info.Keys(argv); ok {
+		return keys, nil
+	}
+	return c.CommandGetKeys(cmds...)
+}
+
+// IsReadOnlyCommand 判断完整命令参数对应的命令是否是只读命令
+func (c *Client) IsReadOnlyCommand(cmds ...interface{}) (bool, error) {
+	info, err := c.lookupCommand(toArgv(cmds...))
+	if err != nil {
+		return false, err
+	}
+	return info.ReadOnly(), nil
+}
+
+func (c *Client) lookupCommand(argv []string) (*command.Info, error) {
+	if len(argv) == 0 {
+		return nil, ErrUnknownCommand
+	}
+	name := strings.ToLower(argv[0])
+	info, loaded := c.commands.get(name)
+	if !loaded {
+		if err := c.RefreshCommands(); err != nil {
+			return nil, err
+		}
+		info, _ = c.commands.get(name)
+	}
+	if info == nil {
+		return nil, ErrUnknownCommand
+	}
+	if len(argv) > 1 && len(info.SubCommands) > 0 {
+		if sub := info.SubCommand(argv[1]); sub != nil {
+			info = sub
+		}
+	}
+	return info, nil
+}
+
+func toArgv(cmds ...interface{}) []string {
+	cmd := args.Get()
+	cmd.AppendArgs(cmds...)
+	argv := make([]string, len(*cmd))
+	copy(argv, *cmd)
+	args.Put(cmd)
+	return argv
+}
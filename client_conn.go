@@ -3,7 +3,6 @@ package rediss
 import (
 	"time"
 
-	innerBytes "github.com/pyihe/go-pkg/bytes"
 	"github.com/pyihe/go-pkg/errors"
 	"github.com/pyihe/rediss/pool"
 )
@@ -16,6 +15,8 @@ func writeConn(conn *pool.RedisConn, cmd []byte, timeout time.Duration) error {
 func readConn(conn *pool.RedisConn, timeout time.Duration) (*Reply, error) {
 	response, err := readResponse(conn, timeout)
 	if err != nil {
+		// 回复没有被完整读取, 连接中可能残留数据, 不能再被使用
+		conn.MarkBroken(err)
 		return nil, err
 	}
 
@@ -37,9 +38,12 @@ func readResponse(conn *pool.RedisConn, timeout time.Duration) (interface{}, err
 	}
 	switch line[0] {
 	case '+', ':':
-		return newReply(line[1:]), nil
+		// line引用的是连接的读缓冲区, 读取数组的后续元素时可能被覆盖, 所以需要拷贝
+		value := make([]byte, len(line)-1)
+		copy(value, line[1:])
+		return newReply(value), nil
 	case '-':
-		return newReply(nil, innerBytes.String(line[1:])), nil
+		return newReply(nil, string(line[1:])), nil
 	case '$':
 		b, err := readBulkString(conn, line, timeout)
 		if err != nil {
@@ -0,0 +1,71 @@
+package rediss
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyihe/rediss/pool"
+)
+
+// 数组中的Simple String和Integer引用的是连接的读缓冲区, 读取后续元素时缓冲区被覆盖, 之前的元素不能随之改变
+func TestReadArrayOfSimpleReplies(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	// 总长度超过读缓冲区的大小(4096), 保证读取过程中缓冲区被重新填充
+	const n = 200
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", 2*n)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "+simple-%04d-%s\r\n:%d\r\n", i, strings.Repeat("x", 32), i)
+	}
+	payload := []byte(b.String())
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write(payload)
+		}
+	}()
+
+	p, err := pool.New(&pool.Config{
+		Dialer:      func() (net.Conn, error) { return net.Dial("tcp", ln.Addr().String()) },
+		MaxConnSize: 1,
+		MinConnSize: 1,
+	})
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	defer p.Close()
+	conn, err := p.Dial()
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	reply, err := readConn(conn, time.Second)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(reply.Array) != 2*n {
+		t.Fatalf("expect %d elements, got %d", 2*n, len(reply.Array))
+	}
+	for i := 0; i < n; i++ {
+		want := fmt.Sprintf("simple-%04d-%s", i, strings.Repeat("x", 32))
+		if got := reply.Array[2*i].ValueString(); got != want {
+			t.Fatalf("element %d: expect %q, got %q", 2*i, want, got)
+		}
+		if got := reply.Array[2*i+1].ValueString(); got != strconv.Itoa(i) {
+			t.Fatalf("element %d: expect %d, got %q", 2*i+1, i, got)
+		}
+	}
+}
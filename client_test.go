@@ -0,0 +1,980 @@
+package rediss
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/pool"
+)
+
+// 用于测试的简易Redis服务器, 只解析请求并记录每个命令的调用次数
+type fakeServer struct {
+	ln          net.Listener
+	rejectHello bool // 模拟不支持HELLO的旧版本服务器
+
+	mu        sync.Mutex
+	version   string // 通过HELLO或者INFO返回的服务器版本, 为空时不返回
+	password  string // 需要认证的密码, 为空时不需要认证
+	authEpoch int    // 增加后所有连接都需要重新认证, 用于模拟NOAUTH
+	commands  map[string]int
+	total     int
+	failures  map[string]int // 命令在成功前需要返回LOADING错误的次数
+	monitor   []string       // MONITOR回复OK之后推送的记录
+}
+
+func newFakeServer(t testing.TB, rejectHello bool) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeServer{ln: ln, rejectHello: rejectHello, commands: make(map[string]int), failures: make(map[string]int)}
+	go s.serve()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commands[name]
+}
+
+func (s *fakeServer) totalCommands() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := -1 // 连接认证时的authEpoch
+	multi := false
+	for {
+		argv, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(argv[0])
+		s.mu.Lock()
+		s.commands[name]++
+		s.total++
+		fail := s.failures[name] > 0
+		if fail {
+			s.failures[name]--
+		}
+		version, password, epoch := s.version, s.password, s.authEpoch
+		s.mu.Unlock()
+
+		var reply string
+		if password != "" {
+			if pass, ok := authPassword(name, argv); ok {
+				if pass != password {
+					reply = "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+				} else {
+					authed = epoch
+				}
+			} else if authed != epoch && name != "HELLO" {
+				reply = "-NOAUTH Authentication required.\r\n"
+			}
+		}
+		switch {
+		case reply != "":
+		case name == "MULTI":
+			multi, reply = true, "+OK\r\n"
+		case name == "EXEC" || name == "DISCARD":
+			multi, reply = false, "+OK\r\n"
+		case multi:
+			reply = "+QUEUED\r\n"
+		case fail:
+			reply = "-LOADING Redis is loading the dataset in memory\r\n"
+		case name == "HELLO" && s.rejectHello:
+			reply = "-ERR unknown command 'HELLO'\r\n"
+		case name == "HELLO" && version != "":
+			reply = fmt.Sprintf("*4\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$%d\r\n%s\r\n", len(version), version)
+		case name == "HELLO":
+			reply = "*0\r\n"
+		case name == "INFO" && version != "":
+			info := "# Server\r\nredis_version:" + version + "\r\n"
+			reply = fmt.Sprintf("$%d\r\n%s\r\n", len(info), info)
+		case name == "PING":
+			reply = "+PONG\r\n"
+		case name == "MONITOR":
+			reply = "+OK\r\n"
+			s.mu.Lock()
+			for _, line := range s.monitor {
+				reply += "+" + line + "\r\n"
+			}
+			s.mu.Unlock()
+		case name == "BLPOP":
+			// 模拟阻塞直到超时
+			timeout, _ := strconv.ParseFloat(argv[len(argv)-1], 64)
+			time.Sleep(time.Duration(timeout * float64(time.Second)))
+			reply = "*-1\r\n"
+		default:
+			reply = "+OK\r\n"
+		}
+		if _, err = conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// 从AUTH或者HELLO命令中获取密码
+func authPassword(name string, argv []string) (string, bool) {
+	switch name {
+	case "AUTH":
+		return argv[len(argv)-1], true
+	case "HELLO":
+		for i := 1; i+2 < len(argv); i++ {
+			if strings.ToUpper(argv[i]) == "AUTH" {
+				return argv[i+2], true
+			}
+		}
+	}
+	return "", false
+}
+
+func (s *fakeServer) setPassword(password string, resetAuth bool) {
+	s.mu.Lock()
+	s.password = password
+	if resetAuth {
+		s.authEpoch++
+	}
+	s.mu.Unlock()
+}
+
+func (s *fakeServer) setVersion(version string) {
+	s.mu.Lock()
+	s.version = version
+	s.mu.Unlock()
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	argv := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if _, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		argv = append(argv, strings.TrimSuffix(arg, "\r\n"))
+	}
+	return argv, nil
+}
+
+func TestHandshakeOncePerConn(t *testing.T) {
+	s := newFakeServer(t, false)
+	c, err := New(WithAddress(s.addr()), WithPassword("pass"), WithClientName("test"),
+		WithDatabase(1), WithPoolSize(1), WithMinConnNum(1))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err = c.DoCommand("PING"); err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+	}
+	if s.count("HELLO") != 1 || s.count("SELECT") != 1 || s.count("CLIENT") != 1 || s.count("PING") != 3 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+}
+
+func TestHandshakeWithoutHello(t *testing.T) {
+	s := newFakeServer(t, true)
+	c, err := New(WithAddress(s.addr()), WithPassword("pass"), WithClientName("test"),
+		WithPoolSize(1), WithMinConnNum(1))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	// CLIENT SETNAME 和 CLIENT SETINFO, 数据库为0时不发送SELECT
+	if s.count("AUTH") != 1 || s.count("CLIENT") != 2 || s.count("SELECT") != 0 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+}
+
+func TestCallOptionView(t *testing.T) {
+	s := newFakeServer(t, false)
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1), WithReadTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	view := c.With(DB(2), CallTimeout(50*time.Millisecond), NoRetry())
+	if view.readTimeout != 50*time.Millisecond || c.readTimeout != time.Second || c.db() != 0 {
+		t.Fatalf("view should not modify client")
+	}
+	// 只有连接的数据库与视图不一致时才发送SELECT
+	for i := 0; i < 2; i++ {
+		if _, err = view.DoCommand("PING"); err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+	}
+	if s.count("SELECT") != 1 {
+		t.Fatalf("expect 1 SELECT, got %d", s.count("SELECT"))
+	}
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if s.count("SELECT") != 2 {
+		t.Fatalf("expect 2 SELECT, got %d", s.count("SELECT"))
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	s := newFakeServer(t, false)
+	policy := &RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1), WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	s.mu.Lock()
+	s.failures["GET"], s.failures["INCR"] = 2, 2
+	s.mu.Unlock()
+
+	// 幂等的命令将被重试
+	if _, err = c.DoCommand("GET", "key"); err != nil {
+		t.Fatalf("get should be retried: %v", err)
+	}
+	// 非幂等的命令默认不重试
+	if _, err = c.DoCommand("INCR", "key"); err == nil || !isRetryableError(err) {
+		t.Fatalf("incr should not be retried: %v", err)
+	}
+	if _, err = c.With(RetryUnsafe()).DoCommand("INCR", "key"); err != nil {
+		t.Fatalf("incr should be retried with RetryUnsafe: %v", err)
+	}
+	if s.count("GET") != 3 || s.count("INCR") != 3 {
+		t.Fatalf("unexpected commands: GET %d, INCR %d", s.count("GET"), s.count("INCR"))
+	}
+
+	// 带有NX、GET等可选项的SET不幂等, 不带可选项时可以重试
+	s.mu.Lock()
+	s.failures["SET"] = 2
+	s.mu.Unlock()
+	if _, err = c.DoCommand("SET", "key", "value", "nx"); err == nil || !isRetryableError(err) {
+		t.Fatalf("set nx should not be retried: %v", err)
+	}
+	if _, err = c.DoCommand("SET", "key", "value", "EX", "10"); err != nil {
+		t.Fatalf("set should be retried: %v", err)
+	}
+	if s.count("SET") != 3 {
+		t.Fatalf("unexpected commands: SET %d", s.count("SET"))
+	}
+
+	// 调用的上下文取消后不再等待重试
+	slow, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1),
+		WithRetryPolicy(&RetryPolicy{MaxRetries: 3, MinBackoff: 2 * time.Second, MaxBackoff: 2 * time.Second}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer slow.Close()
+	s.mu.Lock()
+	s.failures["GET"] = 3
+	s.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err = slow.With(CallContext(ctx)).DoCommand("GET", "key"); err == nil {
+		t.Fatalf("get should fail after context is done")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || s.count("GET") != 4 {
+		t.Fatalf("retry should stop after context is done: %v, GET %d", elapsed, s.count("GET"))
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	// 监听后立即关闭, 拨号将被拒绝
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	var mu sync.Mutex
+	var changes []string
+	hook := &breakerHook{}
+	c, err := New(WithAddress(addr), WithRetryPolicy(nil), WithHooks(hook), WithCircuitBreaker(BreakerConfig{
+		ConsecutiveFailures: 2,
+		OpenTimeout:         20 * time.Millisecond,
+		OnStateChange: func(from, to BreakerState) {
+			mu.Lock()
+			changes = append(changes, from.String()+"->"+to.String())
+			mu.Unlock()
+		},
+	}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err = c.DoCommand("PING"); err == nil || err == ErrCircuitOpen {
+			t.Fatalf("expect dial error, got %v", err)
+		}
+	}
+	if _, err = c.DoCommand("PING"); err != ErrCircuitOpen || c.BreakerState() != BreakerOpen {
+		t.Fatalf("breaker should be open: %v", err)
+	}
+
+	// 半开状态下的试探请求失败, 熔断器重新打开
+	time.Sleep(30 * time.Millisecond)
+	if _, err = c.DoCommand("PING"); err == nil || err == ErrCircuitOpen {
+		t.Fatalf("expect trial request, got %v", err)
+	}
+	if c.BreakerState() != BreakerOpen {
+		t.Fatalf("breaker should reopen, got %v", c.BreakerState())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(changes, ",") != "closed->open,open->half-open,half-open->open" {
+		t.Fatalf("unexpected state changes: %v", changes)
+	}
+	// Hook同样收到状态变化
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if strings.Join(hook.changes, ",") != strings.Join(changes, ",") {
+		t.Fatalf("unexpected hook state changes: %v", hook.changes)
+	}
+}
+
+// 关闭状态时放行、在半开状态下才结束的请求不能作为试探请求, 否则熔断器会在没有试探的情况下关闭
+func TestCircuitBreakerTrials(t *testing.T) {
+	cb := newCircuitBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Millisecond})
+	stale, err := cb.allow()
+	if err != nil || stale {
+		t.Fatalf("expect a normal request, got %v %v", stale, err)
+	}
+	trial, _ := cb.allow()
+	cb.done(trial, io.EOF)
+	if cb.State() != BreakerOpen {
+		t.Fatalf("breaker should be open, got %v", cb.State())
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	if trial, err = cb.allow(); err != nil || !trial || cb.State() != BreakerHalfOpen {
+		t.Fatalf("expect a trial request, got %v %v %v", trial, err, cb.State())
+	}
+	// 熔断前放行的请求成功或者失败都不影响半开状态
+	cb.done(stale, nil)
+	if cb.State() != BreakerHalfOpen {
+		t.Fatalf("stale request should not close the breaker, got %v", cb.State())
+	}
+	cb.done(false, io.EOF)
+	if cb.State() != BreakerHalfOpen {
+		t.Fatalf("stale request should not reopen the breaker, got %v", cb.State())
+	}
+	cb.done(trial, nil)
+	if cb.State() != BreakerClosed {
+		t.Fatalf("trial request should close the breaker, got %v", cb.State())
+	}
+}
+
+type breakerHook struct {
+	BaseHook
+	mu      sync.Mutex
+	changes []string
+}
+
+func (h *breakerHook) BreakerStateChanged(from, to BreakerState) {
+	h.mu.Lock()
+	h.changes = append(h.changes, from.String()+"->"+to.String())
+	h.mu.Unlock()
+}
+
+type recordHook struct {
+	BaseHook
+	mu     sync.Mutex
+	events []string
+}
+
+func (h *recordHook) record(event string) {
+	h.mu.Lock()
+	h.events = append(h.events, event)
+	h.mu.Unlock()
+}
+
+func (h *recordHook) BeforeProcess(ctx context.Context, cmd *Cmd) (context.Context, error) {
+	h.record("before:" + strings.Join(cmd.Args, " "))
+	if cmd.Name == "fail" {
+		return ctx, errors.New("injected")
+	}
+	return ctx, nil
+}
+
+func (h *recordHook) AfterProcess(_ context.Context, cmd *Cmd) {
+	h.record("after:" + cmd.Name + ":" + cmd.Reply.ValueString())
+}
+
+func (h *recordHook) AfterProcessPipeline(_ context.Context, cmds []*Cmd) {
+	h.record("pipeline:" + strconv.Itoa(len(cmds)))
+}
+
+func (h *recordHook) AfterDial(_ context.Context, addr string, _ time.Duration, err error) {
+	if err == nil {
+		h.record("dial")
+	}
+}
+
+func TestHook(t *testing.T) {
+	s := newFakeServer(t, false)
+	h := &recordHook{}
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1), WithHooks(h))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	// BeforeProcess返回错误时命令不会被发送
+	if _, err = c.DoCommand("FAIL"); err == nil || err.Error() != "injected" {
+		t.Fatalf("expect injected error, got %v", err)
+	}
+	replies, err := c.DoPipeline([]interface{}{"SET", "k", "v"}, []interface{}{"PING"})
+	if err != nil || len(replies) != 2 || replies[1].ValueString() != "PONG" {
+		t.Fatalf("pipeline: %v, %v", replies, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// 连接在后台预热, 拨号事件与命令事件的顺序不确定
+	var events []string
+	var dials int
+	for _, e := range h.events {
+		if e == "dial" {
+			dials++
+			continue
+		}
+		events = append(events, e)
+	}
+	expect := "before:PING,after:ping:PONG,before:FAIL,pipeline:2"
+	if got := strings.Join(events, ","); got != expect || dials != 1 {
+		t.Fatalf("unexpected events: %v", h.events)
+	}
+	if s.count("FAIL") != 0 {
+		t.Fatalf("failed command should not be sent")
+	}
+}
+
+func TestBlockingCommandPool(t *testing.T) {
+	s := newFakeServer(t, false)
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1), WithReadTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		// 阻塞时长超过了readTimeout, 但不应该超时
+		_, err := c.BLPop([]string{"list"}, 0.2)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// 阻塞命令不占用普通连接池的连接
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping while blocking: %v", err)
+	}
+	if err = <-done; err != nil && err != NilReply {
+		t.Fatalf("blpop: %v", err)
+	}
+	if stats := c.BlockingPoolStats(); stats == nil || stats.TotalConns != 1 {
+		t.Fatalf("unexpected blocking pool stats: %+v", stats)
+	}
+}
+
+// 对比每个连接只握手一次与每次获取连接时都进行检查(PING、AUTH、SELECT)的往返次数
+func BenchmarkHandshake(b *testing.B) {
+	run := func(b *testing.B, opts ...Option) {
+		s := newFakeServer(b, false)
+		opts = append(opts, WithAddress(s.addr()), WithPassword("pass"), WithDatabase(1))
+		c, err := New(opts...)
+		if err != nil {
+			b.Fatalf("new client: %v", err)
+		}
+		defer c.Close()
+
+		b.ResetTimer()
+		start := s.totalCommands()
+		for i := 0; i < b.N; i++ {
+			if _, err = c.DoCommand("GET", "key"); err != nil {
+				b.Fatalf("get: %v", err)
+			}
+		}
+		b.StopTimer()
+		b.ReportMetric(float64(s.totalCommands()-start)/float64(b.N), "roundtrips/op")
+	}
+
+	b.Run("OncePerConn", func(b *testing.B) {
+		run(b)
+	})
+	b.Run("EveryCheckout", func(b *testing.B) {
+		var c *Client
+		check := WithOnCheckout(func(conn *pool.RedisConn) {
+			_, _ = c.execConn(conn, args.Command("PING"))
+			_ = c.auth(conn, c.username, c.password)
+			_, _ = c.execConn(conn, args.Command("SELECT", c.db()))
+		})
+		capture := func(client *Client) { c = client }
+		run(b, check, capture)
+	})
+}
+
+func TestServerVersion(t *testing.T) {
+	s := newFakeServer(t, false)
+	s.setVersion("6.2.0")
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if v := c.ServerVersion(); v != (Version{Major: 6, Minor: 2}) {
+		t.Fatalf("unexpected version: %v", v)
+	}
+
+	var unsupported *ErrUnsupportedCommand
+	if _, err = c.LMPop([]string{"list"}, "LEFT", 1); !errors.As(err, &unsupported) || unsupported.Cmd != "lmpop" || unsupported.Need != (Version{Major: 7}) {
+		t.Fatalf("expect unsupported lmpop, got %v", err)
+	}
+	if _, err = c.Expire("key", 10, "NX"); !errors.As(err, &unsupported) || unsupported.Cmd != "expire NX" {
+		t.Fatalf("expect unsupported expire NX, got %v", err)
+	}
+	if _, err = c.DoPipeline([]interface{}{"PING"}, []interface{}{"CLUSTER", "SHARDS"}); !errors.As(err, &unsupported) || unsupported.Cmd != "cluster shards" {
+		t.Fatalf("expect unsupported cluster shards, got %v", err)
+	}
+	if _, err = c.Expire("key", 10, ""); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if s.count("LMPOP") != 0 || s.count("CLUSTER") != 0 || s.count("EXPIRE") != 1 {
+		t.Fatalf("unsupported commands should not be sent: %v", s.commands)
+	}
+}
+
+func TestServerVersionWithoutHello(t *testing.T) {
+	s := newFakeServer(t, true)
+	s.setVersion("5.0.14")
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	if _, err = c.ZPopMin("zset", 1); err != nil && err != NilReply {
+		t.Fatalf("zpopmin: %v", err)
+	}
+	if v := c.ServerVersion(); v != (Version{Major: 5, Patch: 14}) {
+		t.Fatalf("unexpected version: %v", v)
+	}
+	var unsupported *ErrUnsupportedCommand
+	if _, err = c.Copy("a", "b", 0, false); !errors.As(err, &unsupported) {
+		t.Fatalf("expect unsupported copy, got %v", err)
+	}
+
+	// 只检查可选项所在的位置, 与可选项同名的参数不受影响
+	if err = c.checkSupported(args.Command("SCAN", "0", "MATCH", "type", "COUNT", "10")); err != nil {
+		t.Fatalf("scan match type: %v", err)
+	}
+	if err = c.checkSupported(args.Command("SCAN", "0", "TYPE", "string")); !errors.As(err, &unsupported) || unsupported.Cmd != "scan TYPE" {
+		t.Fatalf("expect unsupported scan TYPE, got %v", err)
+	}
+	if err = c.checkSupported(args.Command("MIGRATE", "host", "6379", "", "0", "1000", "AUTH", "auth2", "KEYS", "auth2", "a")); err != nil {
+		t.Fatalf("migrate auth: %v", err)
+	}
+	if err = c.checkSupported(args.Command("ZRANGE", "zset", "0", "-1", "WITHSCORES")); err != nil {
+		t.Fatalf("zrange: %v", err)
+	}
+
+	// LPUSH从v2.4.0开始支持多个元素, LPUSHX从v4.0.0开始支持多个元素
+	c.server.set(Version{Major: 3, Minor: 2}, nil)
+	if err = c.checkSupported(args.Command("LPUSH", "list", "a", "b")); err != nil {
+		t.Fatalf("lpush: %v", err)
+	}
+	if err = c.checkSupported(args.Command("LPUSHX", "list", "a", "b")); !errors.As(err, &unsupported) || unsupported.Cmd != "lpushx" {
+		t.Fatalf("expect unsupported lpushx, got %v", err)
+	}
+}
+
+func TestCredentialsProvider(t *testing.T) {
+	s := newFakeServer(t, false)
+	s.setPassword("p1", false)
+
+	var (
+		mu       sync.Mutex
+		password = "p1"
+		calls    int
+	)
+	provider := func(context.Context) (string, string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return "user", password, nil
+	}
+	rotate := func(p string) {
+		mu.Lock()
+		password = p
+		mu.Unlock()
+	}
+
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1), WithCredentialsProvider(provider))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	// 轮换后已有的连接在下次使用前重新认证
+	rotate("p2")
+	s.setPassword("p2", false)
+	c.RefreshCredentials()
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping after rotation: %v", err)
+	}
+	if s.count("AUTH") != 1 {
+		t.Fatalf("expect reauth, got %v", s.commands)
+	}
+
+	// 连接失去认证后重新认证并重新执行命令
+	s.setPassword("p2", true)
+	if _, err = c.DoCommand("GET", "key"); err != nil {
+		t.Fatalf("get after NOAUTH: %v", err)
+	}
+	if s.count("AUTH") != 2 || s.count("GET") != 2 {
+		t.Fatalf("expect resend after auth, got %v", s.commands)
+	}
+
+	// 握手时凭证已经过期, 重新获取后再试一次
+	mu.Lock()
+	calls = 0
+	mu.Unlock()
+	stale := true
+	c2, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1), WithCredentialsProvider(func(ctx context.Context) (string, string, error) {
+		if stale {
+			stale = false
+			return "user", "p1", nil
+		}
+		return provider(ctx)
+	}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c2.Close()
+	if _, err = c2.DoCommand("PING"); err != nil {
+		t.Fatalf("ping with stale credentials: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Fatalf("expect credentials to be refreshed once, got %d", calls)
+	}
+}
+
+func TestDatabaseView(t *testing.T) {
+	s := newFakeServer(t, false)
+	c, err := New(WithAddress(s.addr()), WithDatabase(20), WithPoolSize(1), WithMinConnNum(1))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	// 握手时选择数据库20, 之后的命令不再发送SELECT
+	for i := 0; i < 2; i++ {
+		if _, err = c.DoCommand("PING"); err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+	}
+	if s.count("SELECT") != 1 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+
+	// 视图共享连接, 连接的数据库不一致时才切换
+	view := c.DB(63)
+	for i := 0; i < 2; i++ {
+		if _, err = view.DoCommand("PING"); err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+	}
+	if s.count("SELECT") != 2 || c.db() != 20 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+
+	// 直接发送的SELECT会被记录(发送前连接先从63切换回20), 之后的命令再切换回客户端的数据库
+	if _, err = c.DoCommand("SELECT", 5); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if s.count("SELECT") != 5 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+
+	// 事务中的SELECT回复QUEUED, 不会被当作已经切换, 而是在下次使用前重新SELECT
+	if _, err = c.DoPipeline([]interface{}{"MULTI"}, []interface{}{"SELECT", 20}, []interface{}{"DISCARD"}); err != nil {
+		t.Fatalf("pipeline: %v", err)
+	}
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if s.count("SELECT") != 7 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+
+	if err = c.Select(32); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if c.db() != 32 {
+		t.Fatalf("select should change the default database")
+	}
+	if err = c.Select(-1); err != ErrInvalidDatabase {
+		t.Fatalf("expect invalid database, got %v", err)
+	}
+
+	// Select与创建视图并发执行
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			_ = c.Select(i)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if db := c.With(NoRetry()).db(); db < 0 || db > 32 {
+			t.Fatalf("unexpected database: %d", db)
+		}
+	}
+	wg.Wait()
+}
+
+func TestConn(t *testing.T) {
+	s := newFakeServer(t, false)
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1), WithPoolTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	cn, err := c.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	if err = cn.Select(3); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if _, err = cn.DoCommand("CLIENT", "SETNAME", "pinned"); err != nil {
+		t.Fatalf("client setname: %v", err)
+	}
+	if c.db() != 0 {
+		t.Fatalf("conn should not modify client")
+	}
+	// 直接发送的SELECT改变Conn的数据库, 之后的命令不会切换回原来的数据库
+	if _, err = cn.DoCommand("SELECT", 5); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if _, err = cn.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if s.count("SELECT") != 2 || cn.db() != 5 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+	// 连接被Conn占用, 连接池中没有其他连接
+	if _, err = c.DoCommand("PING"); err != pool.ErrPoolTimeout {
+		t.Fatalf("expect pool timeout, got %v", err)
+	}
+
+	// 通过Conn创建的视图的Close只归还连接, 不会关闭客户端
+	cn.DB(7).Close()
+	if err = cn.Close(); err != ErrConnClosed {
+		t.Fatalf("expect closed, got %v", err)
+	}
+	if _, err = cn.DoCommand("PING"); err != ErrConnClosed {
+		t.Fatalf("expect closed, got %v", err)
+	}
+	// RESET之后重新握手, 连接回到数据库0, 不需要SELECT
+	if _, err = c.DoCommand("PING"); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if s.count("RESET") != 1 || s.count("HELLO") != 2 || s.count("SELECT") != 2 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+
+	// 服务器不支持RESET时关闭连接而不是归还
+	s2 := newFakeServer(t, false)
+	s2.setVersion("6.0.0")
+	c2, err := New(WithAddress(s2.addr()), WithPoolSize(1), WithMinConnNum(1))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c2.Close()
+	cn, err = c2.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	id := cn.ID()
+	if err = cn.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	cn, err = c2.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	defer cn.Close()
+	if cn.ID() == id || s2.count("RESET") != 0 {
+		t.Fatalf("expect a new connection, got %d (old %d), commands: %v", cn.ID(), id, s2.commands)
+	}
+}
+
+// Conn归还前的RESET清除了OnConnect设置的状态, 需要像新建的连接一样再次调用OnConnect
+func TestConnCloseRunsOnConnect(t *testing.T) {
+	s := newFakeServer(t, false)
+	var (
+		mu    sync.Mutex
+		calls int
+		fail  error
+	)
+	onConnect := func(conn *pool.RedisConn) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return fail
+	}
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1), WithMinConnNum(1), WithOnConnect(onConnect))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	cn, err := c.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	id := cn.ID()
+	if err = cn.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	mu.Lock()
+	if calls != 2 {
+		t.Fatalf("expect OnConnect to run again after reset, got %d calls", calls)
+	}
+	// OnConnect失败时连接被关闭而不是归还
+	fail = errors.New("on connect failed")
+	mu.Unlock()
+
+	cn, err = c.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	if cn.ID() != id {
+		t.Fatalf("expect the same connection, got %d (old %d)", cn.ID(), id)
+	}
+	if err = cn.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	mu.Lock()
+	fail = nil
+	mu.Unlock()
+	cn, err = c.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	defer cn.Close()
+	if cn.ID() == id {
+		t.Fatalf("expect a new connection after OnConnect failed")
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	s := newFakeServer(t, false)
+	s.monitor = []string{
+		`1339518083.107412 [3 127.0.0.1:60866] "set" "k" "v"`,
+		`not a monitor line`,
+	}
+	c, err := New(WithAddress(s.addr()), WithPoolSize(1))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, err := c.Monitor(ctx)
+	if err != nil {
+		t.Fatalf("monitor: %v", err)
+	}
+	line := <-lines
+	if line.Err != nil || line.DB != 3 || line.Addr != "127.0.0.1:60866" || strings.Join(line.Args, " ") != "set k v" ||
+		!line.Time.Equal(time.Unix(1339518083, 107412000)) {
+		t.Fatalf("unexpected line: %+v", line)
+	}
+	// 无法解析的记录同样推送给调用方
+	if line = <-lines; line.Err == nil || line.Raw != "not a monitor line" {
+		t.Fatalf("unexpected line: %+v", line)
+	}
+
+	// ctx结束后连接被关闭, 通道随之关闭
+	cancel()
+	select {
+	case line, ok := <-lines:
+		if ok {
+			t.Fatalf("unexpected line: %+v", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("channel should be closed after ctx is done")
+	}
+	if s.count("MONITOR") != 1 {
+		t.Fatalf("unexpected commands: %v", s.commands)
+	}
+}
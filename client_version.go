@@ -0,0 +1,293 @@
+package rediss
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/pool"
+)
+
+// Version 服务器版本
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion 解析形如7.0.11的版本号, 缺少的部分视为0
+func ParseVersion(s string) (v Version, err error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ".", 3)
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if *nums[i], err = strconv.Atoi(part); err != nil {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// IsZero 版本是否未知
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Less 判断v是否低于o
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+// Module 服务器加载的模块
+type Module struct {
+	Name    string
+	Version int
+}
+
+// ErrUnsupportedCommand 服务器版本不支持命令或者命令的可选项
+type ErrUnsupportedCommand struct {
+	Cmd  string  // 命令名称, 如果是可选项不支持, 格式为: 命令名称 可选项
+	Need Version // 需要的最低版本
+	Have Version // 服务器的版本
+}
+
+func (e *ErrUnsupportedCommand) Error() string {
+	return fmt.Sprintf("%s requires redis %s or later, server version is %s", e.Cmd, e.Need, e.Have)
+}
+
+// 服务器信息, 每个新连接握手时更新, 由客户端及其视图共享
+type serverInfo struct {
+	mu      sync.RWMutex
+	version Version
+	modules []Module
+}
+
+func (s *serverInfo) get() (Version, []Module) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version, s.modules
+}
+
+func (s *serverInfo) set(version Version, modules []Module) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version, s.modules = version, modules
+}
+
+// ServerVersion 返回服务器的版本, 在建立第一个连接之前或者无法识别时返回零值
+func (c *Client) ServerVersion() Version {
+	v, _ := c.server.get()
+	return v
+}
+
+// ServerModules 返回服务器加载的模块, 只有支持HELLO命令(redis60.0.0)的服务器才能获取
+func (c *Client) ServerModules() []Module {
+	_, modules := c.server.get()
+	return append([]Module(nil), modules...)
+}
+
+// 从HELLO的回复中解析服务器信息, 回复为键值对交替出现的数组:
+// server redis version 7.0.11 proto 2 id 3 mode standalone role master modules [...]
+func (c *Client) parseHello(reply *Reply) {
+	if reply == nil {
+		return
+	}
+	var (
+		version Version
+		modules []Module
+		fields  = reply.Array
+	)
+	for i := 0; i+1 < len(fields); i += 2 {
+		switch strings.ToLower(fields[i].ValueString()) {
+		case "version":
+			version, _ = ParseVersion(fields[i+1].ValueString())
+		case "modules":
+			for _, m := range fields[i+1].Array {
+				modules = append(modules, parseModule(m))
+			}
+		}
+	}
+	if !version.IsZero() {
+		c.server.set(version, modules)
+	}
+}
+
+// 模块信息为键值对交替出现的数组: name ReJSON ver 20008 ...
+func parseModule(reply *Reply) (m Module) {
+	for i := 0; i+1 < len(reply.Array); i += 2 {
+		switch strings.ToLower(reply.Array[i].ValueString()) {
+		case "name":
+			m.Name = reply.Array[i+1].ValueString()
+		case "ver":
+			m.Version, _ = strconv.Atoi(reply.Array[i+1].ValueString())
+		}
+	}
+	return
+}
+
+// 不支持HELLO的服务器通过INFO server获取版本, 只需要在版本未知时执行一次
+func (c *Client) detectVersion(conn *pool.RedisConn) error {
+	if !c.ServerVersion().IsZero() {
+		return nil
+	}
+	reply, err := c.execConn(conn, args.Command("INFO", "server"))
+	if err != nil {
+		// INFO失败(比如被ACL禁止)时不影响连接的使用
+		if conn.Err() != nil {
+			return err
+		}
+		return nil
+	}
+	for _, line := range strings.Split(reply.ValueString(), "\n") {
+		if v := strings.TrimPrefix(strings.TrimSpace(line), "redis_version:"); len(v) < len(strings.TrimSpace(line)) {
+			if version, err := ParseVersion(v); err == nil {
+				c.server.set(version, nil)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// 命令可选项的版本要求
+type optionRule struct {
+	from    int                // 可选项开始的位置, 命令名称的位置为0
+	leading bool               // 可选项只出现在其他参数之前, 遇到第一个不是可选项的参数时停止检查
+	options map[string]Version // 可选项(大写)需要的最低版本
+	values  map[string]int     // 带有参数的可选项(包括不需要检查版本的)及其参数的数量, 参数不会被当作可选项, -1表示之后的参数都不是可选项
+	argc    int                // 参数数量(包括命令名称)超过argc时需要argcVer版本, 为0时不检查
+	argcVer Version
+}
+
+var (
+	redis30  = Version{Major: 3}
+	redis306 = Version{Major: 3, Patch: 6}
+	redis40  = Version{Major: 4}
+	redis407 = Version{Major: 4, Patch: 7}
+	redis50  = Version{Major: 5}
+	redis60  = Version{Major: 6}
+	redis62  = Version{Major: 6, Minor: 2}
+	redis70  = Version{Major: 7}
+)
+
+// 命令需要的最低版本, 与各命令注释中的"vX.Y.Z后可用"一致, 只列出redis30.0.0及之后的命令
+// 带有子命令的命令使用"命令 子命令"作为key
+var commandVersions = map[string]Version{
+	"wait": redis30, "cluster countkeysinslot": redis30, "cluster failover": redis30, "cluster forget": redis30,
+	"cluster getkeysinslot": redis30, "cluster info": redis30, "cluster keyslot": redis30, "cluster meet": redis30,
+	"cluster myid": redis30, "cluster nodes": redis30, "cluster replicate": redis30, "cluster setslot": redis30,
+
+	"bitfield": {3, 2, 0}, "geoadd": {3, 2, 0}, "geodist": {3, 2, 0}, "geohash": {3, 2, 0}, "geopos": {3, 2, 0},
+	"georadius": {3, 2, 0}, "georadiusbymember": {3, 2, 0}, "hstrlen": {3, 2, 0}, "sentinel info-cache": {3, 2, 0},
+	"touch": {3, 2, 1}, "georadius_ro": {3, 2, 10},
+
+	"object freq": redis40, "unlink": redis40, "swapdb": redis40, "memory doctor": redis40, "memory help": redis40,
+	"memory malloc-stats": redis40, "memory purge": redis40, "memory stats": redis40, "memory usage": redis40,
+
+	"sentinel replicas": redis50, "replicaof": redis50, "bzpopmax": redis50, "bzpopmin": redis50, "zpopmax": redis50, "zpopmin": redis50,
+
+	"lpos": {6, 0, 6},
+
+	"bitfield_ro": redis62, "copy": redis62, "object help": redis62, "geosearch": redis62, "geosearchstore": redis62,
+	"hrandfield": redis62, "blmove": redis62, "lmove": redis62, "pubsub help": redis62, "failover": redis62, "smismember": redis62,
+	"zdiff": redis62, "zdiffstore": redis62, "zinter": redis62, "zmscore": redis62, "zrandmember": redis62, "zrangestore": redis62,
+	"zunion": redis62, "getdel": redis62, "getex": redis62, "reset": redis62,
+
+	"cluster addslotsrange": redis70, "cluster shards": redis70, "sort_ro": redis70, "expiretime": redis70, "pexpiretime": redis70,
+	"blmpop": redis70, "lmpop": redis70, "pubsub shardchannels": redis70, "pubsub shardnumsub": redis70, "spublish": redis70,
+	"command docs": redis70, "command list": redis70, "sintercard": redis70, "bzmpop": redis70, "zintercard": redis70, "zmpop": redis70,
+	"lcs": redis70,
+
+	"waitaof": {7, 2, 0},
+}
+
+// 带有子命令的命令
+var containerCommands = map[string]bool{
+	"cluster": true, "object": true, "memory": true, "pubsub": true, "command": true, "sentinel": true,
+}
+
+// GEORADIUS、GEORADIUSBYMEMBER中带有参数的可选项
+var georadiusValues = map[string]int{"COUNT": 1, "STORE": 1, "STOREDIST": 1}
+
+// 可选项需要的最低版本, 与各命令注释中的"vX.Y.Z开始增加/支持"一致
+var optionVersions = map[string]*optionRule{
+	"set":               {from: 3, options: map[string]Version{"GET": redis62, "EXAT": redis62, "PXAT": redis62, "KEEPTTL": redis60}, values: map[string]int{"EX": 1, "PX": 1, "EXAT": 1, "PXAT": 1}},
+	"expire":            {from: 3, options: map[string]Version{"NX": redis70, "XX": redis70, "GT": redis70, "LT": redis70}},
+	"expireat":          {from: 3, options: map[string]Version{"NX": redis70, "XX": redis70, "GT": redis70, "LT": redis70}},
+	"pexpire":           {from: 3, options: map[string]Version{"NX": redis70, "XX": redis70, "GT": redis70, "LT": redis70}},
+	"pexpireat":         {from: 3, options: map[string]Version{"NX": redis70, "XX": redis70, "GT": redis70, "LT": redis70}},
+	"bitcount":          {from: 4, options: map[string]Version{"BYTE": redis70, "BIT": redis70}},
+	"bitpos":            {from: 5, options: map[string]Version{"BYTE": redis70, "BIT": redis70}},
+	"migrate":           {from: 6, options: map[string]Version{"COPY": redis30, "REPLACE": redis30, "KEYS": redis306, "AUTH": redis407, "AUTH2": redis60}, values: map[string]int{"AUTH": 1, "AUTH2": 2, "KEYS": -1}},
+	"restore":           {from: 4, options: map[string]Version{"REPLACE": redis30, "ABSTTL": redis50, "IDLETIME": redis50, "FREQ": redis50}, values: map[string]int{"IDLETIME": 1, "FREQ": 1}},
+	"scan":              {from: 2, options: map[string]Version{"TYPE": redis60}, values: map[string]int{"MATCH": 1, "COUNT": 1, "TYPE": 1}},
+	"flushall":          {from: 1, options: map[string]Version{"ASYNC": redis40, "SYNC": redis62}},
+	"flushdb":           {from: 1, options: map[string]Version{"ASYNC": redis40, "SYNC": redis62}},
+	"geoadd":            {from: 2, leading: true, options: map[string]Version{"NX": redis62, "XX": redis62, "CH": redis62}},
+	"georadius":         {from: 6, options: map[string]Version{"ANY": redis62}, values: georadiusValues},
+	"georadius_ro":      {from: 6, options: map[string]Version{"ANY": redis62}, values: georadiusValues},
+	"georadiusbymember": {from: 5, options: map[string]Version{"ANY": redis62}, values: georadiusValues},
+	"zrange":            {from: 4, options: map[string]Version{"BYSCORE": redis62, "BYLEX": redis62, "REV": redis62, "LIMIT": redis62}, values: map[string]int{"LIMIT": 2}},
+	"shutdown":          {from: 1, options: map[string]Version{"NOW": redis70, "FORCE": redis70, "ABORT": redis70}},
+	"lpop":              {argc: 2, argcVer: redis62},
+	"rpop":              {argc: 2, argcVer: redis62},
+	"lpushx":            {argc: 3, argcVer: redis40},
+	"rpushx":            {argc: 3, argcVer: redis40},
+}
+
+// 检查服务器版本是否支持命令, 服务器版本未知时不检查
+func (c *Client) checkSupported(cmd []byte) error {
+	have := c.ServerVersion()
+	if have.IsZero() {
+		return nil
+	}
+
+	name := commandName(cmd)
+	need, ok := commandVersions[name]
+	rule := optionVersions[name]
+	if !ok && rule == nil && !containerCommands[name] {
+		return nil
+	}
+	if ok && have.Less(need) {
+		return &ErrUnsupportedCommand{Cmd: name, Need: need, Have: have}
+	}
+
+	argv := decodeCommand(cmd)
+	if containerCommands[name] && len(argv) > 1 {
+		sub := name + " " + strings.ToLower(argv[1])
+		if need, ok = commandVersions[sub]; ok && have.Less(need) {
+			return &ErrUnsupportedCommand{Cmd: sub, Need: need, Have: have}
+		}
+	}
+	if rule == nil {
+		return nil
+	}
+	if rule.argc > 0 && len(argv) > rule.argc && have.Less(rule.argcVer) {
+		return &ErrUnsupportedCommand{Cmd: name, Need: rule.argcVer, Have: have}
+	}
+	// 只检查可选项所在的位置, 可选项的参数(如SCAN的MATCH pattern)即使与可选项同名也不检查
+	for i := rule.from; rule.options != nil && i < len(argv); i++ {
+		option := strings.ToUpper(argv[i])
+		need, ok = rule.options[option]
+		if ok && have.Less(need) {
+			return &ErrUnsupportedCommand{Cmd: name + " " + option, Need: need, Have: have}
+		}
+		n, hasValue := rule.values[option]
+		if n < 0 || (!ok && !hasValue && rule.leading) {
+			break
+		}
+		i += n
+	}
+	return nil
+}
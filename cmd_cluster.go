@@ -0,0 +1,323 @@
+package rediss
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/model/cluster"
+)
+
+// 迁移哈希槽时每批迁移的默认key数量
+const defaultReshardBatchSize = 100
+
+// ClusterAddSlotsRange v7.0.0开始可用
+// 命令格式: CLUSTER ADDSLOTSRANGE start-slot end-slot [start-slot end-slot ...]
+// 时间复杂度: O(N), N为所有区间中哈希槽的总数
+// 与CLUSTER ADDSLOTS类似, 将指定区间内的哈希槽分配给当前节点, 区间包含起始和结束的哈希槽
+// 如果任何一个哈希槽已经被分配给其他节点(包括当前节点), 命令将会失败
+// 返回值类型: Simple String
+func (c *Client) ClusterAddSlotsRange(ranges ...cluster.SlotRange) error {
+	if len(ranges) == 0 {
+		return ErrEmptyOptionArgument
+	}
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "ADDSLOTSRANGE")
+	for _, r := range ranges {
+		cmd.AppendArgs(r.Start, r.End)
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// ClusterCountKeysInSlot v3.0.0后可用
+// 命令格式: CLUSTER COUNTKEYSINSLOT slot
+// 时间复杂度: O(1)
+// 返回当前节点中指定哈希槽的key数量, 该命令只查询当前连接的节点, 如果哈希槽没有分配给当前节点, 则返回0
+// 返回值类型: Integer
+func (c *Client) ClusterCountKeysInSlot(slot int64) (int64, error) {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "COUNTKEYSINSLOT")
+	cmd.AppendArgs(slot)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return 0, err
+	}
+	return reply.Integer()
+}
+
+// ClusterFailover v3.0.0后可用
+// 命令格式: CLUSTER FAILOVER [FORCE | TAKEOVER]
+// 时间复杂度: O(1)
+// 只能在副本上执行, 强制副本对其主节点发起手动故障转移
+// 选项:
+// FORCE: 不与主节点进行任何握手, 适用于主节点不可达的情况, 但仍然需要获得大多数主节点的授权
+// TAKEOVER: 不需要集群共识, 副本直接生成新的配置纪元并接管主节点的哈希槽
+// 返回值类型: Simple String, 命令被接受时返回OK, 但故障转移是否成功需要通过CLUSTER NODES等命令确认
+func (c *Client) ClusterFailover(mode string) error {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "FAILOVER")
+	if mode != "" {
+		cmd.Append(mode)
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// ClusterForget v3.0.0后可用
+// 命令格式: CLUSTER FORGET node-id
+// 时间复杂度: O(1)
+// 从当前节点的节点表中移除指定节点, 同时将该节点放入60秒的禁止列表中, 防止通过gossip重新加入;
+// 要从集群中完全移除一个节点, 需要在所有其他节点上执行该命令
+// 返回值类型: Simple String
+func (c *Client) ClusterForget(nodeID string) error {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "FORGET", nodeID)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// ClusterGetKeysInSlot v3.0.0后可用
+// 命令格式: CLUSTER GETKEYSINSLOT slot count
+// 时间复杂度: O(N), N为请求的key数量
+// 返回当前节点中指定哈希槽的key, 最多返回count个; 主要用于迁移哈希槽时获取需要迁移的key
+// 返回值类型: Array
+func (c *Client) ClusterGetKeysInSlot(slot int64, count int64) ([]string, error) {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "GETKEYSINSLOT")
+	cmd.AppendArgs(slot, count)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseStrings(), nil
+}
+
+// ClusterInfo v3.0.0后可用
+// 命令格式: CLUSTER INFO
+// 时间复杂度: O(1)
+// 返回集群的状态信息, 包括集群状态、哈希槽的分配情况、已知节点数量以及纪元等
+// 返回值类型: Bulk String, 由<field>:<value>组成的多行文本
+func (c *Client) ClusterInfo() (*cluster.Info, error) {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "INFO")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseClusterInfo()
+}
+
+// ClusterKeySlot v3.0.0后可用
+// 命令格式: CLUSTER KEYSLOT key
+// 时间复杂度: O(N), N为key的字节数
+// 返回key所属的哈希槽, 如果key包含哈希标签{...}, 则只计算哈希标签中的内容
+// 返回值类型: Integer
+func (c *Client) ClusterKeySlot(key string) (int64, error) {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "KEYSLOT", key)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return 0, err
+	}
+	return reply.Integer()
+}
+
+// ClusterMeet v3.0.0后可用
+// 命令格式: CLUSTER MEET ip port [cluster-bus-port]
+// v4.0.0开始增加cluster-bus-port参数
+// 时间复杂度: O(1)
+// 将开启了集群模式的节点连接到集群中, 节点之间通过gossip协议传播, 所以只需要与集群中的任意一个节点握手即可
+// 函数参数说明:
+// busPort: 集群总线端口, 小于等于0时不添加该参数, 默认为port+10000
+// 返回值类型: Simple String
+func (c *Client) ClusterMeet(host, port string, busPort int64) error {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "MEET", host, port)
+	if busPort > 0 {
+		cmd.AppendArgs(busPort)
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// ClusterMyID v3.0.0后可用
+// 命令格式: CLUSTER MYID
+// 时间复杂度: O(1)
+// 返回当前节点的ID
+// 返回值类型: Bulk String
+func (c *Client) ClusterMyID() (string, error) {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "MYID")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return "", err
+	}
+	return reply.ValueString(), nil
+}
+
+// ClusterNodes v3.0.0后可用
+// 命令格式: CLUSTER NODES
+// 时间复杂度: O(N), N为集群中节点的数量
+// 返回当前节点视角下的集群配置, 包括每个节点的ID、地址、标志、主节点、心跳、纪元、连接状态以及负责的哈希槽
+// 返回值类型: Bulk String, 每行描述一个节点, 这里解析为cluster.Node
+func (c *Client) ClusterNodes() ([]*cluster.Node, error) {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "NODES")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseClusterNodes()
+}
+
+// ClusterReplicate v3.0.0后可用
+// 命令格式: CLUSTER REPLICATE node-id
+// 时间复杂度: O(1)
+// 将当前节点重新配置为指定主节点的副本, 如果当前节点是主节点, 则只有在不负责任何哈希槽且数据集为空时才能成功
+// 返回值类型: Simple String
+func (c *Client) ClusterReplicate(nodeID string) error {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "REPLICATE", nodeID)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// ClusterSetSlot v3.0.0后可用
+// 命令格式: CLUSTER SETSLOT slot IMPORTING node-id | MIGRATING node-id | NODE node-id | STABLE
+// 时间复杂度: O(1)
+// 修改当前节点中哈希槽的状态:
+// MIGRATING: 将哈希槽设置为迁出状态, 当前节点必须是该哈希槽的所有者, node-id为目标节点
+// IMPORTING: 将哈希槽设置为导入状态, node-id为源节点
+// STABLE: 清除哈希槽的迁出或者导入状态
+// NODE: 将哈希槽分配给指定节点
+// 函数参数说明:
+// subCommand: IMPORTING, MIGRATING, NODE, STABLE
+// nodeID: 对于STABLE子命令将被忽略
+// 返回值类型: Simple String
+func (c *Client) ClusterSetSlot(slot int64, subCommand string, nodeID string) error {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "SETSLOT")
+	cmd.AppendArgs(slot)
+	cmd.Append(subCommand)
+	if !strings.EqualFold(subCommand, "STABLE") {
+		cmd.Append(nodeID)
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// ClusterShards v7.0.0开始可用
+// 命令格式: CLUSTER SHARDS
+// 时间复杂度: O(N), N为集群中节点的数量
+// 返回集群中每个分片的信息, 包括分片负责的哈希槽以及分片中每个节点的属性
+// 返回值类型: Array
+func (c *Client) ClusterShards() ([]*cluster.Shard, error) {
+	cmd := args.Get()
+	cmd.Append("CLUSTER", "SHARDS")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseClusterShards()
+}
+
+// ClusterReshard 将哈希槽区间从当前节点(源节点)迁移到target所连接的节点(目标节点), 对区间中的每个哈希槽依次执行:
+// 1. 在目标节点上执行CLUSTER SETSLOT <slot> IMPORTING <source-id>
+// 2. 在源节点上执行CLUSTER SETSLOT <slot> MIGRATING <target-id>
+// 3. 在源节点上循环执行CLUSTER GETKEYSINSLOT, 并通过MIGRATE将获取到的key迁移到目标节点, 直到哈希槽中没有key
+// 4. 在目标节点和源节点上执行CLUSTER SETSLOT <slot> NODE <target-id>
+// 如果迁移过程中发生错误, 将会立即返回, 此时哈希槽可能处于迁移状态, 可以重新执行本方法继续迁移
+func (c *Client) ClusterReshard(target *Client, option *cluster.ReshardOption) error {
+	if target == nil || option == nil {
+		return ErrEmptyOptionArgument
+	}
+	sourceID, err := c.ClusterMyID()
+	if err != nil {
+		return err
+	}
+	targetID, err := target.ClusterMyID()
+	if err != nil {
+		return err
+	}
+
+	migrate := option.Migrate
+	migrate.Destination = 0 // 集群模式下只有0号数据库
+	if migrate.Host == "" {
+		if migrate.Host, migrate.Port, err = net.SplitHostPort(target.address); err != nil {
+			return err
+		}
+	}
+	batch := option.BatchSize
+	if batch <= 0 {
+		batch = defaultReshardBatchSize
+	}
+
+	for slot := option.Slots.Start; slot <= option.Slots.End; slot++ {
+		if err = target.ClusterSetSlot(slot, "IMPORTING", sourceID); err != nil {
+			return err
+		}
+		if err = c.ClusterSetSlot(slot, "MIGRATING", targetID); err != nil {
+			return err
+		}
+		for {
+			keys, err := c.ClusterGetKeysInSlot(slot, batch)
+			if err != nil {
+				return err
+			}
+			if len(keys) == 0 {
+				break
+			}
+			migrate.Keys = keys
+			if _, err = c.Migrate(&migrate); err != nil {
+				return err
+			}
+		}
+		if err = target.ClusterSetSlot(slot, "NODE", targetID); err != nil {
+			return err
+		}
+		if err = c.ClusterSetSlot(slot, "NODE", targetID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package rediss
 import (
 	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/pyihe/go-pkg/errors"
 	"github.com/pyihe/rediss/args"
@@ -48,18 +49,17 @@ func (c *Client) Auth(username, password string) error {
 // Select v1.0.0后可用
 // 命令格式: SELECT index
 // 时间复杂度: O(1)
+// 修改客户端默认使用的数据库, 对客户端之后的所有命令生效(已经创建的视图除外)
+// 连接池中的连接在下次使用时才会切换数据库, 这里通过一个PING触发切换, 以便数据库索引无效时立即返回错误;
+// 只需要临时在其他数据库上执行命令时, 使用Client.DB创建视图即可
 func (c *Client) Select(database int) error {
-	cmd := args.Get()
-	cmd.Append("SELECT")
-	cmd.AppendArgs(database)
-	cmdBytes := cmd.Bytes()
-	args.Put(cmd)
-
-	_, err := c.sendCommand(cmdBytes)
-	if err != nil {
+	if err := checkDatabase(int32(database)); err != nil {
+		return err
+	}
+	if _, err := c.DB(database).sendCommand(args.Command("PING")); err != nil {
 		return err
 	}
-	atomic.StoreInt32(&c.database, int32(database))
+	atomic.StoreInt32(c.database, int32(database))
 	return nil
 }
 
@@ -418,6 +418,9 @@ func (c *Client) Unlink(keys ...string) (int64, error) {
 // 2. 如果命令作为 MULTI 事务的一部分发送, 则该命令不会阻塞, 而是尽快返回确认先前写入命令的副本数量
 // 3. timeout为0时意味着永久阻塞
 // 4. 由于 WAIT 返回在失败和成功的情况下达到的副本数, 客户端应检查返回的值是否等于或大于它要求的复制级别
+// 5. WAIT依赖于当前连接之前的写命令, 所以不使用阻塞命令专用的连接池, 只将读超时延长为timeout加上余量
+// 6. Client上的命令会落在连接池中任意一个连接上, 此时WAIT等待的不一定是调用方之前的写命令,
+// 需要通过Client.Conn获取固定的连接, 在同一个Conn上执行写命令以及WAIT, 结果才有意义
 // 返回值类型: Integer, 该命令返回在当前连接的上下文中执行的所有写入所达到的副本数
 func (c *Client) Wait(numRep int64, timeout int64) (int64, error) {
 	cmd := args.Get()
@@ -426,7 +429,7 @@ func (c *Client) Wait(numRep int64, timeout int64) (int64, error) {
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
 
-	reply, err := c.sendCommand(cmdBytes)
+	reply, err := c.send(cmdBytes, c.writeTimeout, blockingReadTimeout(time.Duration(timeout)*time.Millisecond))
 	if err != nil {
 		return 0, err
 	}
@@ -25,7 +25,7 @@ func (c *Client) BLMove(src, fromSide, dst, toSide string, timeout float64) (*Re
 	cmd.AppendArgs(timeout)
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
-	return c.sendCommandWithoutTimeout(cmdBytes)
+	return c.sendBlockingCommand(cmdBytes, secondsToDuration(timeout))
 }
 
 // BLMPop v7.0.0后可用
@@ -53,7 +53,7 @@ func (c *Client) BLMPop(timeout float64, keys []string, from string, count int64
 	}
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
-	reply, err := c.sendCommandWithoutTimeout(cmdBytes)
+	reply, err := c.sendBlockingCommand(cmdBytes, secondsToDuration(timeout))
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +77,7 @@ func (c *Client) BLPop(keys []string, timeout float64) (result *list.BPopResult,
 	cmd.AppendArgs(timeout)
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
-	reply, err := c.sendCommandWithoutTimeout(cmdBytes)
+	reply, err := c.sendBlockingCommand(cmdBytes, secondsToDuration(timeout))
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +101,7 @@ func (c *Client) BRPop(keys []string, timeout float64) (result *list.BPopResult,
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
 
-	reply, err := c.sendCommandWithoutTimeout(cmdBytes)
+	reply, err := c.sendBlockingCommand(cmdBytes, secondsToDuration(timeout))
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +124,7 @@ func (c *Client) BRPopLPush(src, dst string, timeout float64) (*Reply, error) {
 	cmd.AppendArgs(timeout)
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
-	return c.sendCommandWithoutTimeout(cmdBytes)
+	return c.sendBlockingCommand(cmdBytes, secondsToDuration(timeout))
 }
 
 // LIndex v1.0.0后可用
@@ -0,0 +1,225 @@
+package rediss
+
+import (
+	"github.com/pyihe/go-pkg/errors"
+	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/model/sentinel"
+)
+
+// CKQuorum v2.8.4后可用
+// 命令格式: SENTINEL CKQUORUM master-name
+// 时间复杂度: O(1)
+// 检查当前的哨兵配置能否达到故障转移所需的法定人数以及授权故障转移所需的多数, 用于检查哨兵部署是否正常
+// 返回值类型: Simple String, 检查通过时返回描述信息, 否则返回错误
+func (s *SentinelClient) CKQuorum(masterName string) (string, error) {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "CKQUORUM", masterName)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := s.client.sendCommand(cmdBytes)
+	if err != nil {
+		return "", err
+	}
+	return reply.ValueString(), nil
+}
+
+// Failover v2.8.4后可用
+// 命令格式: SENTINEL FAILOVER master-name
+// 时间复杂度: O(1)
+// 像主节点不可达一样强制进行故障转移, 并且不需要征求其他哨兵的同意; 新的配置将被广播给其他哨兵
+// 返回值类型: Simple String
+func (s *SentinelClient) Failover(masterName string) error {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "FAILOVER", masterName)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := s.client.sendCommand(cmdBytes)
+	return err
+}
+
+// GetMasterAddrByName v2.8.4后可用
+// 命令格式: SENTINEL GET-MASTER-ADDR-BY-NAME master-name
+// 时间复杂度: O(1)
+// 返回指定名称的主节点的地址和端口, 如果正在进行故障转移或者故障转移已经成功, 则返回被提升的副本的地址和端口
+// 返回值类型: Array, 包含地址和端口两个元素; 如果主节点不存在返回nil
+func (s *SentinelClient) GetMasterAddrByName(masterName string) (host string, port string, err error) {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "GET-MASTER-ADDR-BY-NAME", masterName)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := s.client.sendCommand(cmdBytes)
+	if err != nil {
+		return
+	}
+	if len(reply.Array) != 2 {
+		err = errors.New("invalid master address reply")
+		return
+	}
+	host, port = reply.Array[0].ValueString(), reply.Array[1].ValueString()
+	return
+}
+
+// InfoCache v3.2.0后可用
+// 命令格式: SENTINEL INFO-CACHE [master-name [master-name ...]]
+// 时间复杂度: O(N), N为主节点的数量
+// 返回哨兵缓存的主节点及其副本的INFO输出, 以及缓存的时长; 如果没有指定名称, 则返回所有主节点的缓存
+// 返回值类型: Array
+func (s *SentinelClient) InfoCache(masterNames ...string) ([]*sentinel.InfoCache, error) {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "INFO-CACHE")
+	cmd.Append(masterNames...)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := s.client.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseSentinelInfoCache()
+}
+
+// Master v2.8.4后可用
+// 命令格式: SENTINEL MASTER master-name
+// 时间复杂度: O(1)
+// 返回指定主节点的状态和信息
+// 返回值类型: Array, 由字段名和值交替组成
+func (s *SentinelClient) Master(masterName string) (*sentinel.Master, error) {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "MASTER", masterName)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := s.client.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseSentinelMaster()
+}
+
+// Masters v2.8.4后可用
+// 命令格式: SENTINEL MASTERS
+// 时间复杂度: O(N), N为主节点的数量
+// 返回所有被监控的主节点的状态和信息
+// 返回值类型: Array
+func (s *SentinelClient) Masters() ([]*sentinel.Master, error) {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "MASTERS")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := s.client.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseSentinelMasters()
+}
+
+// Monitor v2.8.4后可用
+// 命令格式: SENTINEL MONITOR name ip port quorum
+// 时间复杂度: O(1)
+// 开始监控一个新的主节点, 主节点的名称为name, quorum为判定客观下线所需的哨兵数量
+// 返回值类型: Simple String
+func (s *SentinelClient) Monitor(masterName string, ip string, port string, quorum int64) error {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "MONITOR", masterName, ip, port)
+	cmd.AppendArgs(quorum)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := s.client.sendCommand(cmdBytes)
+	return err
+}
+
+// Remove v2.8.4后可用
+// 命令格式: SENTINEL REMOVE master-name
+// 时间复杂度: O(1)
+// 停止监控指定的主节点, 并将其从哨兵的内部状态中移除
+// 返回值类型: Simple String
+func (s *SentinelClient) Remove(masterName string) error {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "REMOVE", masterName)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := s.client.sendCommand(cmdBytes)
+	return err
+}
+
+// Replicas v5.0.0后可用
+// 命令格式: SENTINEL REPLICAS master-name
+// 时间复杂度: O(N), N为副本的数量
+// 返回指定主节点的所有副本的状态和信息
+// 返回值类型: Array
+func (s *SentinelClient) Replicas(masterName string) ([]*sentinel.Replica, error) {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "REPLICAS", masterName)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := s.client.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseSentinelReplicas()
+}
+
+// Reset v2.8.4后可用
+// 命令格式: SENTINEL RESET pattern
+// 时间复杂度: O(N), N为匹配的主节点数量
+// 重置所有名称匹配pattern的主节点, 清除主节点的所有状态(包括正在进行的故障转移), 并移除已经发现的副本和哨兵
+// 返回值类型: Integer, 返回被重置的主节点数量
+func (s *SentinelClient) Reset(pattern string) (int64, error) {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "RESET", pattern)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := s.client.sendCommand(cmdBytes)
+	if err != nil {
+		return 0, err
+	}
+	return reply.Integer()
+}
+
+// Sentinels v2.8.4后可用
+// 命令格式: SENTINEL SENTINELS master-name
+// 时间复杂度: O(N), N为哨兵的数量
+// 返回监控指定主节点的其他哨兵的状态和信息
+// 返回值类型: Array
+func (s *SentinelClient) Sentinels(masterName string) ([]*sentinel.Sentinel, error) {
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "SENTINELS", masterName)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := s.client.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseSentinelSentinels()
+}
+
+// Set v2.8.4后可用
+// 命令格式: SENTINEL SET master-name option value [option value ...]
+// 时间复杂度: O(1)
+// 修改指定主节点的监控配置, 如: down-after-milliseconds, failover-timeout, parallel-syncs, quorum, auth-pass等
+// 返回值类型: Simple String
+func (s *SentinelClient) Set(masterName string, options map[string]string) error {
+	if len(options) == 0 {
+		return ErrEmptyOptionArgument
+	}
+	cmd := args.Get()
+	cmd.Append("SENTINEL", "SET", masterName)
+	if err := appendArgs(cmd, options); err != nil {
+		args.Put(cmd)
+		return err
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := s.client.sendCommand(cmdBytes)
+	return err
+}
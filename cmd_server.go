@@ -0,0 +1,569 @@
+package rediss
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pyihe/go-pkg/errors"
+	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/model/command"
+	"github.com/pyihe/rediss/model/server"
+)
+
+// MONITOR推送记录的通道缓冲大小
+const monitorBufferSize = 64
+
+// MemoryDoctor v4.0.0后可用
+// 命令格式: MEMORY DOCTOR
+// 时间复杂度: O(1)
+// 报告Redis服务器遇到的不同内存相关问题, 并提供可能的补救建议
+// 返回值类型: Bulk String
+func (c *Client) MemoryDoctor() (string, error) {
+	cmd := args.Get()
+	cmd.Append("MEMORY", "DOCTOR")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return "", err
+	}
+	return reply.ValueString(), nil
+}
+
+// MemoryHelp v4.0.0后可用
+// 命令格式: MEMORY HELP
+// 时间复杂度: O(1)
+// 返回MEMORY子命令的帮助信息
+// 返回值类型: Array
+func (c *Client) MemoryHelp() (*Reply, error) {
+	cmd := args.Get()
+	cmd.Append("MEMORY", "HELP")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	return c.sendCommand(cmdBytes)
+}
+
+// MemoryMallocStats v4.0.0后可用
+// 命令格式: MEMORY MALLOC-STATS
+// 时间复杂度: 取决于分配了多少内存, 可能很慢
+// 返回内存分配器的内部统计报告, 目前只有使用jemalloc编译时才支持此命令
+// 返回值类型: Bulk String
+func (c *Client) MemoryMallocStats() (string, error) {
+	cmd := args.Get()
+	cmd.Append("MEMORY", "MALLOC-STATS")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return "", err
+	}
+	return reply.ValueString(), nil
+}
+
+// MemoryPurge v4.0.0后可用
+// 命令格式: MEMORY PURGE
+// 时间复杂度: 取决于分配了多少内存, 可能很慢
+// 尝试清除脏页以便内存分配器回收, 目前只有使用jemalloc编译时才支持此命令
+// 返回值类型: Simple String
+func (c *Client) MemoryPurge() error {
+	cmd := args.Get()
+	cmd.Append("MEMORY", "PURGE")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// MemoryStats v4.0.0后可用
+// 命令格式: MEMORY STATS
+// 时间复杂度: O(1)
+// 返回服务器的内存使用情况, 包括各项开销、每个数据库的开销以及数据集大小、碎片率等信息
+// 返回值类型: Array, 由字段名和值交替组成的数组, 其中db.<dbid>的值为描述该数据库开销的嵌套数组
+func (c *Client) MemoryStats() (*server.MemoryStats, error) {
+	cmd := args.Get()
+	cmd.Append("MEMORY", "STATS")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseMemoryStats()
+}
+
+// MemoryUsage v4.0.0后可用
+// 命令格式: MEMORY USAGE key [SAMPLES count]
+// 时间复杂度: O(N), N为样本数量
+// 返回key及其值存储在RAM中所需的字节数, 报告的使用量是key的数据及其管理开销所需的内存分配总和
+// 对于嵌套数据类型, 可以提供可选的SAMPLES选项, 其中count是采样嵌套值的数量, 默认为5; 要对所有嵌套值进行采样, 请使用SAMPLES 0
+// 函数参数说明:
+// samples: 采样数量, 小于0时不添加SAMPLES选项
+// 返回值类型: Integer, 返回内存使用的字节数, 如果key不存在返回nil
+func (c *Client) MemoryUsage(key string, samples int64) (int64, error) {
+	cmd := args.Get()
+	cmd.Append("MEMORY", "USAGE", key)
+	if samples >= 0 {
+		cmd.AppendArgs("SAMPLES", samples)
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return 0, err
+	}
+	return reply.Integer()
+}
+
+// Monitor v1.0.0后可用
+// 命令格式: MONITOR
+// 时间复杂度: N/A
+// MONITOR是一个调试命令, 它会流式传回Redis服务器处理的每个命令, 可以帮助了解数据库发生了什么
+// 由于MONITOR会独占连接并持续接收服务器推送的数据, 所以该命令使用一个不受连接池管理的专用连接,
+// 当ctx结束时连接会被关闭, 返回的通道也随之关闭; 如果连接发生错误, 通道同样会被关闭
+// 注意: 运行MONITOR会降低服务器的吞吐量, 请仅在调试时使用
+// 返回值类型: 服务器先回复OK, 之后每执行一条命令推送一条Simple String, 这里解析为server.MonitorLine,
+// 无法解析的记录的Err不为nil, 原始记录保存在Raw中
+func (c *Client) Monitor(ctx context.Context) (<-chan *server.MonitorLine, error) {
+	conn, err := c.pool.Dial()
+	if err != nil {
+		return nil, err
+	}
+	if err = writeConn(conn, args.Command("MONITOR"), c.writeTimeout); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if _, err = readConn(conn, c.readTimeout); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	lines := make(chan *server.MonitorLine, monitorBufferSize)
+	done := make(chan struct{})
+
+	// ctx结束时关闭连接, 以中断阻塞中的读取
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = conn.Close()
+	}()
+
+	go func() {
+		defer close(lines)
+		defer close(done)
+
+		for {
+			reply, err := readConn(conn, 0)
+			if err != nil {
+				return
+			}
+			raw := string(reply.Value)
+			line, err := parseMonitorLine(raw)
+			if err != nil {
+				// 无法解析的记录同样推送给调用方, 而不是直接丢弃
+				line = &server.MonitorLine{Raw: raw, Err: err}
+			}
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return lines, nil
+}
+
+// Command v2.8.13后可用
+// 命令格式: COMMAND
+// 时间复杂度: O(N), N为Redis命令的总数
+// 返回所有命令的元数据, 包括参数个数、标志、key的位置、ACL分类、key规范以及子命令等
+// 返回值类型: Array, 每个元素为描述一个命令的数组
+func (c *Client) Command() ([]*command.Info, error) {
+	reply, err := c.sendCommand(args.Command("COMMAND"))
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseCommandInfos()
+}
+
+// CommandCount v2.8.13后可用
+// 命令格式: COMMAND COUNT
+// 时间复杂度: O(1)
+// 返回Redis服务器中命令的总数
+// 返回值类型: Integer
+func (c *Client) CommandCount() (int64, error) {
+	cmd := args.Get()
+	cmd.Append("COMMAND", "COUNT")
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return 0, err
+	}
+	return reply.Integer()
+}
+
+// CommandDocs v7.0.0开始可用
+// 命令格式: COMMAND DOCS [command-name [command-name ...]]
+// 时间复杂度: O(N), N为查找的命令数量
+// 返回指定命令的文档信息, 如果没有指定命令, 则返回所有命令的文档
+// 文档包括简要说明、可用版本、分组、时间复杂度、历史变更以及参数描述等
+// 返回值类型: Array, 由命令名称和文档交替组成的数组
+func (c *Client) CommandDocs(names ...string) ([]*command.Doc, error) {
+	cmd := args.Get()
+	cmd.Append("COMMAND", "DOCS")
+	cmd.Append(names...)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseCommandDocs()
+}
+
+// CommandGetKeys v2.8.13后可用
+// 命令格式: COMMAND GETKEYS command [arg [arg ...]]
+// 时间复杂度: O(N), N为命令参数的数量
+// 从完整的Redis命令中提取key, 对于key位置不固定的命令(movablekeys), 这是获取key的唯一方式
+// 返回值类型: Array, 返回命令中的key
+func (c *Client) CommandGetKeys(cmds ...interface{}) ([]string, error) {
+	cmd := args.Get()
+	cmd.Append("COMMAND", "GETKEYS")
+	cmd.AppendArgs(cmds...)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseStrings(), nil
+}
+
+// CommandInfo v2.8.13后可用
+// 命令格式: COMMAND INFO [command-name [command-name ...]]
+// v7.0.0开始command-name为可选参数
+// 时间复杂度: O(N), N为查找的命令数量
+// 返回指定命令的元数据, 格式与COMMAND相同; 如果命令不存在, 对应位置为nil
+// 返回值类型: Array
+func (c *Client) CommandInfo(names ...string) ([]*command.Info, error) {
+	cmd := args.Get()
+	cmd.Append("COMMAND", "INFO")
+	cmd.Append(names...)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseCommandInfos()
+}
+
+// CommandList v7.0.0开始可用
+// 命令格式: COMMAND LIST [FILTERBY MODULE module-name | ACLCAT category | PATTERN pattern]
+// 时间复杂度: O(N), N为Redis命令的总数
+// 返回服务器中所有命令的名称, 可以通过FILTERBY选项按照模块、ACL分类或者模式进行过滤
+// 返回值类型: Array
+func (c *Client) CommandList(filter *command.ListFilter) ([]string, error) {
+	cmd := args.Get()
+	cmd.Append("COMMAND", "LIST")
+	if filter != nil {
+		switch {
+		case filter.Module != "":
+			cmd.Append("FILTERBY", "MODULE", filter.Module)
+		case filter.ACLCat != "":
+			cmd.Append("FILTERBY", "ACLCAT", filter.ACLCat)
+		case filter.Pattern != "":
+			cmd.Append("FILTERBY", "PATTERN", filter.Pattern)
+		}
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseStrings(), nil
+}
+
+// BgRewriteAOF v1.0.0后可用
+// 命令格式: BGREWRITEAOF
+// 时间复杂度: O(1)
+// 指示Redis启动AOF重写过程, 重写将创建当前AOF文件的小型优化版本; 如果BGREWRITEAOF失败, 不会丢失任何数据, 因为旧的AOF将保持不变
+// 只有在没有后台进程进行持久化时才会触发重写, 如果有子进程正在创建快照, 则AOF重写将被调度到快照完成之后执行
+// 返回值类型: Simple String, 返回重写开始或者已经被调度的提示信息
+func (c *Client) BgRewriteAOF() (string, error) {
+	reply, err := c.sendCommand(args.Command("BGREWRITEAOF"))
+	if err != nil {
+		return "", err
+	}
+	return reply.ValueString(), nil
+}
+
+// BgSave v1.0.0后可用
+// 命令格式: BGSAVE [SCHEDULE]
+// v3.2.2开始增加SCHEDULE选项
+// 时间复杂度: O(1)
+// 在后台保存数据库, 父进程继续服务客户端, 子进程将数据库保存到磁盘后退出
+// 如果已经有后台保存在运行或者有其他非后台保存进程在运行(如AOF重写), 将返回错误;
+// 如果指定了SCHEDULE选项, 当AOF重写正在进行时, 该命令会立即返回OK并在下一次机会时调度后台保存
+// 返回值类型: Simple String, 返回保存开始或者已经被调度的提示信息
+func (c *Client) BgSave(schedule bool) (string, error) {
+	cmd := args.Get()
+	cmd.Append("BGSAVE")
+	if schedule {
+		cmd.Append("SCHEDULE")
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.sendCommand(cmdBytes)
+	if err != nil {
+		return "", err
+	}
+	return reply.ValueString(), nil
+}
+
+// DebugSleep v1.0.0后可用
+// 命令格式: DEBUG SLEEP seconds
+// 时间复杂度: O(1)
+// 使服务器阻塞指定的秒数, 期间不处理任何命令, 用于在测试中模拟慢服务器或者触发超时
+// 注意: v7.0.0开始DEBUG命令默认被禁用, 需要在配置中开启enable-debug-command
+// 返回值类型: Simple String
+func (c *Client) DebugSleep(seconds float64) error {
+	cmd := args.Get()
+	cmd.Append("DEBUG", "SLEEP")
+	cmd.AppendArgs(seconds)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.send(cmdBytes, c.writeTimeout, blockingReadTimeout(secondsToDuration(seconds)))
+	return err
+}
+
+// Failover v6.2.0后可用
+// 命令格式: FAILOVER [TO host port [FORCE]] [ABORT] [TIMEOUT milliseconds]
+// 时间复杂度: O(1)
+// 在当前主节点和它的一个副本之间发起协调故障转移, 故障转移不是同步的, 而是启动一个后台任务来处理故障转移:
+// 1. 主节点在内部启动CLIENT PAUSE WRITE, 暂停传入的写入
+// 2. 主节点监视副本, 等待副本确认它已经处理了复制偏移量
+// 3. 主节点和副本交换角色
+// 选项:
+// TO: 指定需要提升为主节点的副本, 默认情况下选择偏移量最大的副本
+// FORCE: 超时后即使副本没有追上也强制进行故障转移
+// ABORT: 中止正在进行的故障转移
+// TIMEOUT: 等待副本追上的最长时间
+// 返回值类型: Simple String, 命令被接受时返回OK
+func (c *Client) Failover(option *server.FailoverOption) error {
+	cmd := args.Get()
+	cmd.Append("FAILOVER")
+	if option != nil {
+		if option.Host != "" {
+			cmd.Append("TO", option.Host, option.Port)
+			if option.Force {
+				cmd.Append("FORCE")
+			}
+		}
+		if option.Abort {
+			cmd.Append("ABORT")
+		}
+		if option.Timeout > 0 {
+			cmd.AppendArgs("TIMEOUT", option.Timeout)
+		}
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// LastSave v1.0.0后可用
+// 命令格式: LASTSAVE
+// 时间复杂度: O(1)
+// 返回最后一次成功执行数据库保存的UNIX时间戳, 客户端可以通过读取LASTSAVE的值, 然后执行BGSAVE, 再每隔几秒检查LASTSAVE是否改变来判断BGSAVE是否成功
+// 返回值类型: Integer, UNIX时间戳, 单位秒
+func (c *Client) LastSave() (int64, error) {
+	reply, err := c.sendCommand(args.Command("LASTSAVE"))
+	if err != nil {
+		return 0, err
+	}
+	return reply.Integer()
+}
+
+// ReplicaOf v5.0.0后可用
+// 命令格式: REPLICAOF host port | NO ONE
+// 时间复杂度: O(1)
+// 修改当前服务器的复制设置:
+// 1. 如果当前服务器已经是某个主节点的副本, REPLICAOF NO ONE将关闭复制, 并将服务器转变为主节点, 已经同步的数据集不会被丢弃
+// 2. REPLICAOF host port将使服务器成为指定服务器的副本, 如果服务器已经是某个主节点的副本, 它将停止对旧主节点的复制并丢弃旧数据集, 开始与新主节点同步
+// 函数参数说明:
+// host为空时, 执行REPLICAOF NO ONE
+// 返回值类型: Simple String
+func (c *Client) ReplicaOf(host, port string) error {
+	cmd := args.Get()
+	cmd.Append("REPLICAOF")
+	if host == "" {
+		cmd.Append("NO", "ONE")
+	} else {
+		cmd.Append(host, port)
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// Role v2.8.12后可用
+// 命令格式: ROLE
+// 时间复杂度: O(1)
+// 返回当前实例的复制角色: master, slave或者sentinel, 以及该角色相关的复制信息
+// 主节点: 复制偏移量以及每个已连接副本的地址、端口和确认的偏移量
+// 副本: 主节点的地址、端口, 复制状态以及接收到的偏移量
+// 哨兵: 监控的主节点名称
+// 返回值类型: Array
+func (c *Client) Role() (*server.Role, error) {
+	reply, err := c.sendCommand(args.Command("ROLE"))
+	if err != nil {
+		return nil, err
+	}
+	return reply.parseRole()
+}
+
+// Save v1.0.0后可用
+// 命令格式: SAVE
+// 时间复杂度: O(N), N为所有数据库中key的总数
+// 同步保存数据集, 以RDB文件的形式生成Redis实例中所有数据的时间点快照
+// 因为会阻塞其他所有客户端, 所以几乎不应该在生产环境中调用SAVE, 而应该使用BGSAVE
+// 返回值类型: Simple String
+func (c *Client) Save() error {
+	_, err := c.sendCommandWithoutTimeout(args.Command("SAVE"))
+	return err
+}
+
+// Shutdown v1.0.0后可用
+// 命令格式: SHUTDOWN [NOSAVE | SAVE] [NOW] [FORCE] [ABORT]
+// v7.0.0开始增加NOW, FORCE以及ABORT选项
+// 时间复杂度: 保存数据时为O(N), N为所有数据库中key的总数, 否则为O(1)
+// 停止所有客户端; 如果配置了保存点则执行SAVE; 如果开启了AOF则刷新AOF文件; 然后退出服务器
+// 选项:
+// SAVE: 即使没有配置保存点也强制保存
+// NOSAVE: 即使配置了保存点也不保存
+// NOW: 不等待滞后的副本
+// FORCE: 忽略保存时的错误
+// ABORT: 取消正在进行的关闭, 不能与其他选项同时使用
+// 返回值类型: 成功时服务器直接关闭连接, 不返回任何值; 失败时返回错误
+func (c *Client) Shutdown(option *server.ShutdownOption) error {
+	cmd := args.Get()
+	cmd.Append("SHUTDOWN")
+	if option != nil {
+		if option.SaveMode != "" {
+			cmd.Append(option.SaveMode)
+		}
+		if option.Now {
+			cmd.Append("NOW")
+		}
+		if option.Force {
+			cmd.Append("FORCE")
+		}
+		if option.Abort {
+			cmd.Append("ABORT")
+		}
+	}
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommandWithoutTimeout(cmdBytes)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return err
+}
+
+// SwapDB v4.0.0后可用
+// 命令格式: SWAPDB index1 index2
+// 时间复杂度: O(N), N为监视这两个数据库中key的客户端数量
+// 交换两个数据库, 连接到其中一个数据库的客户端将立即看到另一个数据库的数据
+// 返回值类型: Simple String
+func (c *Client) SwapDB(index1, index2 int) error {
+	cmd := args.Get()
+	cmd.Append("SWAPDB")
+	cmd.AppendArgs(index1, index2)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	_, err := c.sendCommand(cmdBytes)
+	return err
+}
+
+// Time v2.6.0后可用
+// 命令格式: TIME
+// 时间复杂度: O(1)
+// 返回服务器当前时间
+// 返回值类型: Array, 包含两个元素: UNIX时间戳(秒)以及当前秒已经过去的微秒数
+func (c *Client) Time() (t time.Time, err error) {
+	reply, err := c.sendCommand(args.Command("TIME"))
+	if err != nil {
+		return
+	}
+	if len(reply.Array) != 2 {
+		err = errors.New("invalid time reply")
+		return
+	}
+	sec, err := reply.Array[0].Integer()
+	if err != nil {
+		return
+	}
+	usec, err := reply.Array[1].Integer()
+	if err != nil {
+		return
+	}
+	t = time.Unix(sec, usec*int64(time.Microsecond))
+	return
+}
+
+// WaitAOF v7.2.0后可用
+// 命令格式: WAITAOF numlocal numreplicas timeout
+// 时间复杂度: O(1)
+// 阻塞当前客户端, 直到当前连接之前的所有写命令都被本地以及至少指定数量的副本fsync到AOF中;
+// 如果达到超时(以毫秒为单位), 即使尚未满足要求, 命令也会返回; timeout为0时意味着永久阻塞
+// numlocal只能为0或者1, 为1时要求本地开启了AOF
+// 与WAIT一样依赖于当前连接之前的写命令, 所以不使用阻塞命令专用的连接池, 只将读超时延长为timeout加上余量
+// 注意: Client上的命令会落在连接池中任意一个连接上, 此时WAITAOF等待的不一定是调用方之前的写命令,
+// 需要通过Client.Conn获取固定的连接, 在同一个Conn上执行写命令以及WAITAOF, 结果才有意义
+// 返回值类型: Array, 包含两个元素: 本地fsync的数量(0或者1)以及确认fsync的副本数量
+func (c *Client) WaitAOF(numLocal, numReplicas, timeout int64) (local int64, replicas int64, err error) {
+	cmd := args.Get()
+	cmd.Append("WAITAOF")
+	cmd.AppendArgs(numLocal, numReplicas, timeout)
+	cmdBytes := cmd.Bytes()
+	args.Put(cmd)
+
+	reply, err := c.send(cmdBytes, c.writeTimeout, blockingReadTimeout(time.Duration(timeout)*time.Millisecond))
+	if err != nil {
+		return
+	}
+	if len(reply.Array) != 2 {
+		err = errors.New("invalid waitaof reply")
+		return
+	}
+	if local, err = reply.Array[0].Integer(); err != nil {
+		return
+	}
+	replicas, err = reply.Array[1].Integer()
+	return
+}
@@ -16,7 +16,6 @@ import (
 // 每个元素同样也是一个包含成员以及它的分数的数组
 func (c *Client) BZMPop(timeout float64, keys []string, op string, count int64) (result *sortedset.PopResult, err error) {
 	cmd := args.Get()
-	defer args.Put(cmd)
 	cmd.Append("BZMPOP")
 	cmd.AppendArgs(timeout, len(keys))
 	cmd.Append(keys...)
@@ -27,7 +26,7 @@ func (c *Client) BZMPop(timeout float64, keys []string, op string, count int64)
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
 
-	reply, err := c.sendCommandWithoutTimeout(cmdBytes)
+	reply, err := c.sendBlockingCommand(cmdBytes, secondsToDuration(timeout))
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +48,7 @@ func (c *Client) BZPopMax(keys []string, timeout float64) (result *sortedset.Pop
 	cmd.AppendArgs(timeout)
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
-	reply, err := c.sendCommandWithoutTimeout(cmdBytes)
+	reply, err := c.sendBlockingCommand(cmdBytes, secondsToDuration(timeout))
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +71,7 @@ func (c *Client) BZPopMin(keys []string, timeout float64) (result *sortedset.Pop
 	cmdBytes := cmd.Bytes()
 	args.Put(cmd)
 
-	reply, err := c.sendCommandWithoutTimeout(cmdBytes)
+	reply, err := c.sendBlockingCommand(cmdBytes, secondsToDuration(timeout))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,97 @@
+package rediss
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/pool"
+)
+
+// Conn 固定使用连接池中同一个连接的客户端视图, 拥有Client的全部命令
+// 用于依赖连接状态的操作, 如SELECT、AUTH、CLIENT SETNAME、WATCH、CLIENT TRACKING等,
+// 这些命令在Client上执行时会落在连接池中任意一个连接上, 并在归还后污染其他调用方
+// 在Conn上直接发送的SELECT会改变Conn的数据库, 通过Conn.DB创建的视图则在各自的数据库上执行命令
+// Conn不能被多个goroutine同时使用, 使用完毕后必须调用Close将连接归还给连接池, 通过Conn创建的视图的Close同样只归还连接
+type Conn struct {
+	*Client
+}
+
+// 固定的连接
+type pinnedConn struct {
+	mu     sync.Mutex
+	client *Client // 创建Conn的客户端, 用于在归还前重新握手以及调用OnConnect
+	pool   *pool.Pool
+	conn   *pool.RedisConn
+	db     int32 // Conn的数据库, 由Conn及其没有指定数据库的视图共享, 通过atomic读写
+	closed bool
+}
+
+func (pc *pinnedConn) get() (*pool.RedisConn, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.closed {
+		return nil, ErrConnClosed
+	}
+	// 连接已经不可用, 继续使用只会得到同样的错误
+	if err := pc.conn.Err(); err != nil {
+		return nil, err
+	}
+	return pc.conn, nil
+}
+
+// Conn 从连接池中取出一个连接, 返回固定使用该连接的视图, ctx用于等待空闲连接, 同时作为Conn上所有调用的上下文
+// Conn上的命令不会重试, 因为重试无法更换连接
+func (c *Client) Conn(ctx context.Context) (*Conn, error) {
+	conn, err := c.pool.Get(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	pc := &pinnedConn{client: c, pool: c.pool, conn: conn, db: c.db()}
+	view := c.With(CallContext(ctx), NoRetry())
+	view.database = &pc.db
+	view.pinned = pc
+	return &Conn{Client: view}, nil
+}
+
+// ID 返回固定的连接的ID
+func (cn *Conn) ID() uint64 {
+	return cn.pinned.conn.ID()
+}
+
+// Close 通过RESET清除连接的所有状态(数据库、认证、连接名称、事务、订阅、客户端缓存等), 然后重新握手、调用OnConnect并归还给连接池;
+// 服务器不支持RESET(v6.2.0之前)、重置或者OnConnect失败时, 连接将被关闭而不是带着状态回到连接池
+// 重复调用将返回ErrConnClosed
+func (cn *Conn) Close() error {
+	return cn.pinned.close()
+}
+
+func (pc *pinnedConn) close() error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.closed {
+		return ErrConnClosed
+	}
+	pc.closed = true
+
+	conn := pc.conn
+	if conn.Err() == nil {
+		if err := pc.reset(); err != nil {
+			conn.MarkBroken(err)
+		}
+	}
+	pc.pool.Put(conn)
+	return nil
+}
+
+// 重置连接的状态, RESET会将连接恢复为未认证的默认用户、数据库0以及RESP2,
+// 所以需要像新建的连接一样重新握手并调用WithOnConnect设置的函数
+func (pc *pinnedConn) reset() error {
+	if v := pc.client.ServerVersion(); !v.IsZero() && v.Less(redis62) {
+		return &ErrUnsupportedCommand{Cmd: "reset", Need: redis62, Have: v}
+	}
+	if _, err := pc.client.execConn(pc.conn, args.Command("RESET")); err != nil {
+		return err
+	}
+	return pc.client.poolConfig.OnConnect(pc.conn)
+}
@@ -0,0 +1,105 @@
+package rediss
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/pool"
+)
+
+// CredentialsProvider 返回连接使用的用户名和密码, 用于对接密钥管理服务或者短期有效的令牌
+// 每个新连接握手时都会调用, 因此实现需要自行缓存, 避免频繁访问密钥管理服务
+type CredentialsProvider func(ctx context.Context) (username, password string, err error)
+
+// 凭证的状态, 由客户端及其视图共享
+type credentials struct {
+	provider CredentialsProvider
+	version  uint64 // 每次刷新凭证时加1, 与连接认证时的版本不一致的连接在下次使用前重新认证
+}
+
+// RefreshCredentials 通知客户端凭证已经轮换, 已有的连接将在下次使用前通过AUTH使用新的凭证重新认证,
+// 新的凭证从CredentialsProvider获取, 没有设置CredentialsProvider时不生效
+func (c *Client) RefreshCredentials() {
+	if c.creds.provider != nil {
+		atomic.AddUint64(&c.creds.version, 1)
+	}
+}
+
+// 获取当前的凭证以及版本, 没有设置CredentialsProvider时使用WithUsername、WithPassword设置的凭证
+func (c *Client) credentials() (username, password string, version uint64, err error) {
+	version = atomic.LoadUint64(&c.creds.version)
+	if c.creds.provider == nil {
+		return c.username, c.password, version, nil
+	}
+	username, password, err = c.creds.provider(c.context())
+	return
+}
+
+// 连接认证时使用的凭证已经过期时, 使用新的凭证重新认证
+func (c *Client) reauth(conn *pool.RedisConn) error {
+	if c.creds.provider == nil || conn.AuthVersion() == atomic.LoadUint64(&c.creds.version) {
+		return nil
+	}
+	return c.authConn(conn)
+}
+
+// 获取最新的凭证对连接进行认证
+func (c *Client) authConn(conn *pool.RedisConn) error {
+	username, password, version, err := c.credentials()
+	if err != nil {
+		return err
+	}
+	if err = c.auth(conn, username, password); err != nil {
+		return err
+	}
+	conn.SetAuthVersion(version)
+	return nil
+}
+
+func (c *Client) auth(conn *pool.RedisConn, username, password string) (err error) {
+	if len(password) == 0 {
+		return nil
+	}
+	var cmd []byte
+	if len(username) > 0 {
+		cmd = args.Command("AUTH", username, password)
+	} else {
+		cmd = args.Command("AUTH", password)
+	}
+	_, err = c.execConn(conn, cmd)
+	return
+}
+
+// 命令因为连接未认证而失败时(如服务器重启或者开启了认证), 使用最新的凭证重新认证后再执行一次
+// 服务器对未认证的连接不会执行命令, 所以任何命令都可以安全地重新执行
+func (c *Client) resendAfterAuth(conn *pool.RedisConn, cmd []byte, writeTimeout, readTimeout time.Duration) (*Reply, error) {
+	if err := c.authConn(conn); err != nil {
+		return nil, err
+	}
+	if err := writeConn(conn, cmd, writeTimeout); err != nil {
+		return nil, err
+	}
+	return readConn(conn, readTimeout)
+}
+
+// 是否为连接未认证的错误
+func isNoAuthError(err error) bool {
+	e, ok := err.(RedisError)
+	return ok && e.Code() == "NOAUTH"
+}
+
+// 是否为认证失败的错误, WRONGPASS表示凭证错误, NOAUTH表示连接未认证
+func isAuthError(err error) bool {
+	e, ok := err.(RedisError)
+	if !ok {
+		return false
+	}
+	switch e.Code() {
+	case "WRONGPASS", "NOAUTH":
+		return true
+	}
+	// v6.0.0之前密码错误时返回: ERR invalid password
+	return e == "ERR invalid password"
+}
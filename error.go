@@ -1,9 +1,32 @@
 package rediss
 
-import "github.com/pyihe/go-pkg/errors"
+import (
+	"strings"
+
+	"github.com/pyihe/go-pkg/errors"
+)
 
 var (
 	NilReply               = errors.New("nil reply")
 	ErrNotSupportArgument  = errors.New("not support argument")
 	ErrEmptyOptionArgument = errors.New("option argument cannot be empty")
+	ErrUnknownCommand      = errors.New("unknown command")
+	ErrInvalidDatabase     = errors.New("invalid database")
+	ErrCircuitOpen         = errors.New("circuit breaker is open")
+	ErrConnClosed          = errors.New("connection is closed")
 )
+
+// RedisError 服务器返回的错误回复
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// Code 返回错误码, 即错误信息的第一个单词, 如ERR、WRONGTYPE、MOVED等
+func (e RedisError) Code() string {
+	if i := strings.IndexByte(string(e), ' '); i > 0 {
+		return string(e[:i])
+	}
+	return string(e)
+}
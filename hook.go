@@ -0,0 +1,245 @@
+package rediss
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Hook 命令执行的拦截点, 可用于链路追踪、监控、日志、缓存以及故障注入等
+// 多个Hook按照添加的顺序执行Before, 按照相反的顺序执行After, 只需要关心部分方法时可以嵌入BaseHook
+type Hook interface {
+	// BeforeProcess 在命令发送前调用, 返回错误时命令不会被发送, 该错误将作为命令的结果返回;
+	// 如果设置了cmd.Reply, 命令同样不会被发送, 而是直接使用cmd.Reply和cmd.Err作为结果, 可用于实现缓存
+	BeforeProcess(ctx context.Context, cmd *Cmd) (context.Context, error)
+	// AfterProcess 在命令执行完成后调用, 此时cmd.Duration、cmd.Reply以及cmd.Err已经被设置, 修改它们将改变返回给调用方的结果
+	AfterProcess(ctx context.Context, cmd *Cmd)
+	// BeforeProcessPipeline 在管道中的命令发送前调用, 返回错误时所有命令都不会被发送
+	BeforeProcessPipeline(ctx context.Context, cmds []*Cmd) (context.Context, error)
+	// AfterProcessPipeline 在管道中的命令执行完成后调用
+	AfterProcessPipeline(ctx context.Context, cmds []*Cmd)
+	// BeforeDial 在连接池拨号前调用
+	BeforeDial(ctx context.Context, addr string) context.Context
+	// AfterDial 在连接池拨号完成后调用
+	AfterDial(ctx context.Context, addr string, duration time.Duration, err error)
+}
+
+// BreakerHook 需要感知熔断器状态变化的Hook可以额外实现该接口, 通过AddHook添加后即可生效
+type BreakerHook interface {
+	// BreakerStateChanged 在熔断器的状态变化时调用, 调用时不持有熔断器的锁, 没有开启熔断器时不会调用
+	BreakerStateChanged(from, to BreakerState)
+}
+
+// BaseHook Hook的空实现, 嵌入后只需要实现关心的方法
+type BaseHook struct{}
+
+func (BaseHook) BeforeProcess(ctx context.Context, _ *Cmd) (context.Context, error) {
+	return ctx, nil
+}
+
+func (BaseHook) AfterProcess(context.Context, *Cmd) {}
+
+func (BaseHook) BeforeProcessPipeline(ctx context.Context, _ []*Cmd) (context.Context, error) {
+	return ctx, nil
+}
+
+func (BaseHook) AfterProcessPipeline(context.Context, []*Cmd) {}
+
+func (BaseHook) BeforeDial(ctx context.Context, _ string) context.Context {
+	return ctx
+}
+
+func (BaseHook) AfterDial(context.Context, string, time.Duration, error) {}
+
+// Cmd 传递给Hook的命令信息
+type Cmd struct {
+	Name     string        // 命令名称, 小写
+	Args     []string      // 命令的所有参数, 包括命令名称
+	Addr     string        // 服务器地址
+	ConnID   uint64        // 执行命令的连接ID, 重试时为最后一次使用的连接, 没有获取到连接时为0
+	DB       int           // 执行命令的数据库
+	Start    time.Time     // 开始执行的时间
+	Duration time.Duration // 执行耗时, 包括获取连接以及重试的时间, AfterProcess时有效
+	Reply    *Reply        // 命令的回复, AfterProcess时有效
+	Err      error         // 命令的错误, AfterProcess时有效
+}
+
+func (c *Client) newCmd(cmd []byte) *Cmd {
+	argv := decodeCommand(cmd)
+	hc := &Cmd{
+		Args:  argv,
+		Addr:  c.address,
+		DB:    int(c.db()),
+		Start: time.Now(),
+	}
+	if len(argv) > 0 {
+		hc.Name = commandName(cmd)
+	}
+	return hc
+}
+
+// 已经添加的Hook, 由客户端及其视图共享
+type hookChain struct {
+	mu    sync.RWMutex
+	hooks []Hook
+}
+
+// 返回当前Hook的快照, 执行期间添加的Hook不影响正在执行的命令
+func (hc *hookChain) get() []Hook {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+	return hc.hooks
+}
+
+func (hc *hookChain) add(hooks ...Hook) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	merged := make([]Hook, 0, len(hc.hooks)+len(hooks))
+	merged = append(merged, hc.hooks...)
+	hc.hooks = append(merged, hooks...)
+}
+
+// AddHook 添加Hook, 对客户端及其所有视图生效
+func (c *Client) AddHook(hooks ...Hook) {
+	c.hooks.add(hooks...)
+}
+
+// 执行单个命令的Hook, process为真正执行命令的函数, 没有Hook时传入的hc为nil
+func (c *Client) processHook(cmd []byte, process func(hc *Cmd) (*Reply, error)) (*Reply, error) {
+	hooks := c.hooks.get()
+	if len(hooks) == 0 {
+		return process(nil)
+	}
+
+	var (
+		ctx = c.context()
+		hc  = c.newCmd(cmd)
+		n   int
+		err error
+	)
+	for ; n < len(hooks); n++ {
+		if ctx, err = hooks[n].BeforeProcess(ctx, hc); err != nil {
+			hc.Err = err
+			break
+		}
+	}
+	if err == nil && hc.Reply == nil {
+		hc.Reply, hc.Err = process(hc)
+	}
+	hc.Duration = time.Since(hc.Start)
+	for i := n - 1; i >= 0; i-- {
+		hooks[i].AfterProcess(ctx, hc)
+	}
+	return hc.Reply, hc.Err
+}
+
+// 执行管道的Hook, process为真正执行管道的函数, 没有Hook时传入的hcs为nil
+func (c *Client) processPipelineHook(cmds [][]byte, process func(hcs []*Cmd) ([]*Reply, error)) ([]*Reply, error) {
+	hooks := c.hooks.get()
+	if len(hooks) == 0 {
+		return process(nil)
+	}
+
+	var (
+		ctx = c.context()
+		hcs = make([]*Cmd, len(cmds))
+		n   int
+		err error
+	)
+	for i := range cmds {
+		hcs[i] = c.newCmd(cmds[i])
+	}
+	for ; n < len(hooks); n++ {
+		if ctx, err = hooks[n].BeforeProcessPipeline(ctx, hcs); err != nil {
+			break
+		}
+	}
+	var replies []*Reply
+	if err == nil {
+		replies, err = process(hcs)
+	}
+	for i, hc := range hcs {
+		hc.Duration = time.Since(hc.Start)
+		if i < len(replies) && replies[i] != nil {
+			hc.Reply, hc.Err = replies[i], replies[i].Err
+		} else if err != nil {
+			hc.Err = err
+		} else {
+			hc.Err = NilReply
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		hooks[i].AfterProcessPipeline(ctx, hcs)
+	}
+	return replies, err
+}
+
+// 带有Hook的拨号函数
+func (c *Client) dial() (net.Conn, error) {
+	hooks := c.hooks.get()
+	if len(hooks) == 0 {
+		return c.dialNetwork()
+	}
+
+	ctx, start := context.Background(), time.Now()
+	for _, h := range hooks {
+		ctx = h.BeforeDial(ctx, c.address)
+	}
+	conn, err := c.dialNetwork()
+	duration := time.Since(start)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].AfterDial(ctx, c.address, duration, err)
+	}
+	return conn, err
+}
+
+// 按照网络类型、拨号超时以及TLS配置拨号
+func (c *Client) dialNetwork() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.dialTimeout}
+	if c.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, c.network, c.address, c.tlsConfig)
+	}
+	return dialer.Dial(c.network, c.address)
+}
+
+// 将编码后的命令解码为参数列表, 命令的格式为: *N\r\n$len\r\narg\r\n...
+func decodeCommand(cmd []byte) []string {
+	line, rest := nextLine(cmd)
+	if len(line) < 2 || line[0] != '*' {
+		return nil
+	}
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n <= 0 {
+		return nil
+	}
+	argv := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, rest = nextLine(rest)
+		if len(line) < 2 || line[0] != '$' {
+			return argv
+		}
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < 0 || size > len(rest) {
+			return argv
+		}
+		argv = append(argv, string(rest[:size]))
+		rest = rest[size:]
+		if len(rest) >= 2 {
+			rest = rest[2:]
+		}
+	}
+	return argv
+}
+
+// 返回第一个\r\n之前的内容以及之后剩余的内容
+func nextLine(b []byte) (line, rest []byte) {
+	for i := 0; i+1 < len(b); i++ {
+		if b[i] == '\r' && b[i+1] == '\n' {
+			return b[:i], b[i+2:]
+		}
+	}
+	return b, nil
+}
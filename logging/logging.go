@@ -0,0 +1,253 @@
+// Package logging 提供结构化的命令日志Hook, 用于排查问题
+// 只记录耗时超过阈值的命令, 支持按比例采样, 记录的参数会经过脱敏处理:
+// AUTH、HELLO、MIGRATE、SENTINEL SET等命令中的凭证以及SET、HSET等命令写入的值不会原样出现在日志中
+// Logger接口与log/slog的Logger.Log方法一致, Level的取值也与slog.Level相同, 适配slog时只需要转换Level的类型
+package logging
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/pyihe/rediss"
+)
+
+// Level 日志级别, 取值与slog.Level一致
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// Logger 结构化日志的输出, args为交替出现的键值对
+type Logger interface {
+	Log(ctx context.Context, level Level, msg string, args ...interface{})
+}
+
+// LoggerFunc 将函数适配为Logger
+type LoggerFunc func(ctx context.Context, level Level, msg string, args ...interface{})
+
+func (f LoggerFunc) Log(ctx context.Context, level Level, msg string, args ...interface{}) {
+	f(ctx, level, msg, args...)
+}
+
+// 日志记录的键
+const (
+	KeyCommand   = "command"
+	KeyKey       = "key"
+	KeyArgs      = "args"
+	KeyDuration  = "duration"
+	KeyReplySize = "reply_size"
+	KeyError     = "error"
+	KeyConnID    = "conn_id"
+	KeyPipeline  = "pipeline"
+	KeyFrom      = "from"
+	KeyTo        = "to"
+)
+
+// 脱敏后的参数
+const redacted = rediss.RedactedArg
+
+type Option func(h *Hook)
+
+// WithSlowThreshold 只记录耗时不小于threshold的命令, 默认为0, 即记录所有命令
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(h *Hook) {
+		h.threshold = threshold
+	}
+}
+
+// WithSampleRate 按比例采样, rate的取值范围为[0, 1], 默认为1, 即不采样
+func WithSampleRate(rate float64) Option {
+	return func(h *Hook) {
+		h.sampleRate = rate
+	}
+}
+
+// WithLevel 设置成功的命令的日志级别, 默认为LevelInfo, 失败的命令固定使用LevelError
+func WithLevel(level Level) Option {
+	return func(h *Hook) {
+		h.level = level
+	}
+}
+
+// WithArgs 记录脱敏后的命令参数, 默认只记录命令名称以及key
+func WithArgs() Option {
+	return func(h *Hook) {
+		h.logArgs = true
+	}
+}
+
+// WithRedactAll 隐藏key之后的所有参数, 只对WithArgs生效
+func WithRedactAll() Option {
+	return func(h *Hook) {
+		h.redactAll = true
+	}
+}
+
+// Hook 命令日志的Hook, 通过rediss.WithHooks或者Client.AddHook添加
+type Hook struct {
+	rediss.BaseHook
+
+	logger     Logger
+	threshold  time.Duration
+	sampleRate float64
+	level      Level
+	logArgs    bool
+	redactAll  bool
+}
+
+func New(logger Logger, opts ...Option) *Hook {
+	h := &Hook{
+		logger:     logger,
+		sampleRate: 1,
+		level:      LevelInfo,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *Hook) AfterProcess(ctx context.Context, cmd *rediss.Cmd) {
+	if !h.sampled(cmd.Duration) {
+		return
+	}
+	h.log(ctx, cmd, false)
+}
+
+// AfterProcessPipeline 管道按照整体的耗时判断是否记录, 记录时管道中的每个命令各输出一条日志
+func (h *Hook) AfterProcessPipeline(ctx context.Context, cmds []*rediss.Cmd) {
+	if len(cmds) == 0 || !h.sampled(cmds[0].Duration) {
+		return
+	}
+	for _, cmd := range cmds {
+		h.log(ctx, cmd, true)
+	}
+}
+
+// BreakerStateChanged 记录熔断器的状态变化, 不受阈值以及采样的影响, 熔断时使用LevelWarn, 其他变化使用LevelInfo
+func (h *Hook) BreakerStateChanged(from, to rediss.BreakerState) {
+	level := LevelInfo
+	if to == rediss.BreakerOpen {
+		level = LevelWarn
+	}
+	h.logger.Log(context.Background(), level, "redis circuit breaker state changed", KeyFrom, from.String(), KeyTo, to.String())
+}
+
+// 判断是否需要记录
+func (h *Hook) sampled(duration time.Duration) bool {
+	if duration < h.threshold || h.sampleRate <= 0 {
+		return false
+	}
+	return h.sampleRate >= 1 || rand.Float64() < h.sampleRate
+}
+
+func (h *Hook) log(ctx context.Context, cmd *rediss.Cmd, pipeline bool) {
+	attrs := make([]interface{}, 0, 16)
+	attrs = append(attrs, KeyCommand, cmd.Name)
+	if key, ok := commandKey(cmd); ok {
+		attrs = append(attrs, KeyKey, key)
+	}
+	if h.logArgs && len(cmd.Args) > 1 {
+		attrs = append(attrs, KeyArgs, strings.Join(h.redact(cmd), " "))
+	}
+	attrs = append(attrs, KeyDuration, cmd.Duration, KeyReplySize, replySize(cmd.Reply), KeyConnID, cmd.ConnID)
+	if pipeline {
+		attrs = append(attrs, KeyPipeline, true)
+	}
+
+	level := h.level
+	if cmd.Err != nil && cmd.Err != rediss.NilReply {
+		level = LevelError
+		attrs = append(attrs, KeyError, cmd.Err.Error())
+	}
+	h.logger.Log(ctx, level, "redis command", attrs...)
+}
+
+// 第一个参数不是key或者是敏感数据的命令
+var keylessCommands = map[string]bool{
+	"auth": true, "hello": true, "migrate": true, "select": true, "swapdb": true,
+	"ping": true, "echo": true, "quit": true, "reset": true,
+	"acl": true, "client": true, "config": true, "command": true, "cluster": true, "sentinel": true,
+	"debug": true, "info": true, "latency": true, "memory": true, "module": true, "slowlog": true,
+	"eval": true, "eval_ro": true, "evalsha": true, "evalsha_ro": true, "script": true,
+	"fcall": true, "fcall_ro": true, "function": true,
+	"publish": true, "spublish": true, "subscribe": true, "psubscribe": true, "ssubscribe": true,
+	"unsubscribe": true, "punsubscribe": true, "sunsubscribe": true, "pubsub": true,
+	"multi": true, "exec": true, "discard": true, "unwatch": true,
+	"wait": true, "waitaof": true, "monitor": true, "shutdown": true, "failover": true,
+	"replicaof": true, "slaveof": true, "flushall": true, "flushdb": true, "dbsize": true,
+	"save": true, "bgsave": true, "bgrewriteaof": true, "lastsave": true, "time": true,
+	"scan": true, "randomkey": true, "keys": true, "lolwut": true,
+	"blmpop": true, "bzmpop": true, "lmpop": true, "zmpop": true, "sintercard": true, "zintercard": true, "zunion": true, "zinter": true, "zdiff": true,
+}
+
+// 命令操作的key, 只取第一个参数
+func commandKey(cmd *rediss.Cmd) (string, bool) {
+	if len(cmd.Args) < 2 || keylessCommands[cmd.Name] {
+		return "", false
+	}
+	return cmd.Args[1], true
+}
+
+// 对命令的参数(不包括命令名称)进行脱敏, 凭证由rediss.Cmd.RedactedArgs隐藏, 这里额外隐藏写入的值
+func (h *Hook) redact(cmd *rediss.Cmd) []string {
+	args := cmd.RedactedArgs()[1:]
+	if h.redactAll {
+		start := 0
+		if _, ok := commandKey(cmd); ok {
+			start = 1
+		}
+		redactFrom(args, start)
+		return args
+	}
+
+	switch cmd.Name {
+	case "set", "setnx", "getset", "append":
+		// SET key value [options...]
+		redactAt(args, 1)
+	case "setex", "psetex", "setrange", "hsetnx":
+		// SETEX key seconds value、SETRANGE key offset value、HSETNX key field value
+		redactAt(args, 2)
+	case "mset", "msetnx":
+		// MSET key value [key value ...]
+		for i := 1; i < len(args); i += 2 {
+			args[i] = redacted
+		}
+	case "hset", "hmset":
+		// HSET key field value [field value ...]
+		for i := 2; i < len(args); i += 2 {
+			args[i] = redacted
+		}
+	}
+	return args
+}
+
+func redactAt(args []string, i int) {
+	if i < len(args) {
+		args[i] = redacted
+	}
+}
+
+func redactFrom(args []string, start int) {
+	for i := start; i < len(args); i++ {
+		args[i] = redacted
+	}
+}
+
+// 回复的大小, 即所有值的字节数之和
+func replySize(reply *rediss.Reply) int {
+	if reply == nil {
+		return 0
+	}
+	size := len(reply.Value)
+	for _, r := range reply.Array {
+		size += replySize(r)
+	}
+	return size
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyihe/rediss"
+)
+
+type record struct {
+	level Level
+	attrs map[string]interface{}
+}
+
+type recorder struct {
+	records []record
+}
+
+func (r *recorder) Log(_ context.Context, level Level, _ string, args ...interface{}) {
+	attrs := make(map[string]interface{})
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs[args[i].(string)] = args[i+1]
+	}
+	r.records = append(r.records, record{level: level, attrs: attrs})
+}
+
+func TestHookThreshold(t *testing.T) {
+	r := &recorder{}
+	h := New(r, WithSlowThreshold(10*time.Millisecond))
+
+	h.AfterProcess(context.Background(), &rediss.Cmd{Name: "get", Args: []string{"GET", "fast"}, Duration: time.Millisecond})
+	h.AfterProcess(context.Background(), &rediss.Cmd{
+		Name:     "get",
+		Args:     []string{"GET", "slow"},
+		ConnID:   7,
+		Duration: 20 * time.Millisecond,
+		Reply:    &rediss.Reply{Array: []*rediss.Reply{{Value: []byte("abc")}, {Value: []byte("de")}}},
+		Err:      rediss.RedisError("WRONGTYPE Operation against a key holding the wrong kind of value"),
+	})
+
+	if len(r.records) != 1 {
+		t.Fatalf("expect 1 record, got %d", len(r.records))
+	}
+	rec := r.records[0]
+	if rec.level != LevelError || rec.attrs[KeyKey] != "slow" || rec.attrs[KeyReplySize] != 5 || rec.attrs[KeyConnID] != uint64(7) {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	if _, ok := rec.attrs[KeyArgs]; ok {
+		t.Fatalf("args should not be logged by default: %+v", rec)
+	}
+}
+
+func TestHookBreakerStateChanged(t *testing.T) {
+	r := &recorder{}
+	// 通过可选接口调用, 与熔断器通知Hook的方式一致
+	var h rediss.BreakerHook = New(r, WithSlowThreshold(time.Second), WithSampleRate(0))
+
+	h.BreakerStateChanged(rediss.BreakerClosed, rediss.BreakerOpen)
+	h.BreakerStateChanged(rediss.BreakerOpen, rediss.BreakerHalfOpen)
+	if len(r.records) != 2 || r.records[0].level != LevelWarn || r.records[1].level != LevelInfo {
+		t.Fatalf("unexpected records: %+v", r.records)
+	}
+	if r.records[0].attrs[KeyFrom] != "closed" || r.records[0].attrs[KeyTo] != "open" {
+		t.Fatalf("unexpected attrs: %v", r.records[0].attrs)
+	}
+}
+
+func TestHookSampleRate(t *testing.T) {
+	r := &recorder{}
+	h := New(r, WithSampleRate(0))
+	h.AfterProcess(context.Background(), &rediss.Cmd{Name: "ping", Args: []string{"PING"}})
+	if len(r.records) != 0 {
+		t.Fatalf("expect no record, got %d", len(r.records))
+	}
+}
+
+func TestHookRedaction(t *testing.T) {
+	cases := []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{"AUTH", "user", "secret"}, "? ?"},
+		{[]string{"HELLO", "3", "AUTH", "user", "secret", "SETNAME", "app"}, "3 AUTH ? ? SETNAME app"},
+		{[]string{"MIGRATE", "host", "6379", "key", "0", "1000", "AUTH2", "user", "secret"}, "host 6379 key 0 1000 AUTH2 ? ?"},
+		{[]string{"MIGRATE", "host", "6379", "", "0", "1000", "auth", "secret", "KEYS", "a", "b"}, "host 6379  0 1000 auth ? KEYS a b"},
+		{[]string{"SET", "key", "secret", "EX", "10"}, "key ? EX 10"},
+		{[]string{"HSET", "key", "f1", "secret", "f2", "secret"}, "key f1 ? f2 ?"},
+		{[]string{"MSET", "k1", "secret", "k2", "secret"}, "k1 ? k2 ?"},
+		{[]string{"CONFIG", "SET", "requirepass", "secret"}, "SET requirepass ?"},
+		{[]string{"SENTINEL", "SET", "mymaster", "auth-pass", "secret"}, "SET mymaster auth-pass ?"},
+		{[]string{"GET", "key"}, "key"},
+	}
+	for _, c := range cases {
+		r := &recorder{}
+		h := New(r, WithArgs())
+		h.AfterProcessPipeline(context.Background(), []*rediss.Cmd{{Name: strings.ToLower(c.args[0]), Args: c.args}})
+		rec := r.records[0]
+		if rec.attrs[KeyArgs] != c.expect || rec.attrs[KeyPipeline] != true {
+			t.Fatalf("%v: expect %q, got %+v", c.args, c.expect, rec.attrs)
+		}
+		if _, ok := rec.attrs[KeyKey]; ok && (c.args[0] == "AUTH" || c.args[0] == "HELLO" || c.args[0] == "MIGRATE") {
+			t.Fatalf("%v: unexpected key: %+v", c.args, rec.attrs)
+		}
+	}
+
+	r := &recorder{}
+	New(r, WithArgs(), WithRedactAll()).AfterProcess(context.Background(), &rediss.Cmd{Name: "lpush", Args: []string{"LPUSH", "key", "a", "b"}})
+	if r.records[0].attrs[KeyArgs] != "key ? ?" {
+		t.Fatalf("unexpected args: %+v", r.records[0].attrs)
+	}
+}
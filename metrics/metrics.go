@@ -0,0 +1,188 @@
+// Package metrics 提供命令以及连接池的监控指标
+// Hook记录每个命令的耗时、错误以及管道的大小, 通过WatchPool在采集时读取连接池的统计信息,
+// 指标存储在可替换的Registry中, 内置的TextRegistry以Prometheus文本格式输出指标, 不依赖任何第三方库
+package metrics
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pyihe/rediss"
+	"github.com/pyihe/rediss/pool"
+)
+
+// 指标名称
+const (
+	CommandDuration  = "rediss_command_duration_seconds"
+	CommandErrors    = "rediss_command_errors_total"
+	PipelineSize     = "rediss_pipeline_size"
+	PipelineDuration = "rediss_pipeline_duration_seconds"
+	DialErrors       = "rediss_dial_errors_total"
+	BreakerState     = "rediss_circuit_breaker_state"
+
+	PoolIdleConns    = "rediss_pool_idle_connections"
+	PoolInUseConns   = "rediss_pool_in_use_connections"
+	PoolTargetConns  = "rediss_pool_target_connections"
+	PoolHits         = "rediss_pool_hits_total"
+	PoolMisses       = "rediss_pool_misses_total"
+	PoolWaits        = "rediss_pool_waits_total"
+	PoolWaitDuration = "rediss_pool_wait_duration_seconds_total"
+	PoolTimeouts     = "rediss_pool_timeouts_total"
+	PoolStaleConns   = "rediss_pool_stale_connections_total"
+)
+
+var (
+	// DefaultLatencyBuckets 命令耗时直方图默认的桶, 单位为秒
+	DefaultLatencyBuckets = []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+	// DefaultSizeBuckets 管道大小直方图默认的桶
+	DefaultSizeBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+)
+
+type Option func(h *Hook)
+
+// WithLatencyBuckets 设置命令耗时直方图的桶, 单位为秒
+func WithLatencyBuckets(buckets []float64) Option {
+	return func(h *Hook) {
+		h.latencyBuckets = buckets
+	}
+}
+
+// Hook 监控指标的Hook, 通过rediss.WithHooks或者Client.AddHook添加
+type Hook struct {
+	rediss.BaseHook
+
+	registry       Registry
+	latencyBuckets []float64
+}
+
+func New(registry Registry, opts ...Option) *Hook {
+	h := &Hook{
+		registry:       registry,
+		latencyBuckets: DefaultLatencyBuckets,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	descs := []*Desc{
+		{Name: CommandDuration, Help: "Duration of redis commands in seconds.", Kind: Histogram, Labels: []string{"command"}, Buckets: h.latencyBuckets},
+		{Name: CommandErrors, Help: "Number of failed redis commands by error code.", Kind: Counter, Labels: []string{"command", "code"}},
+		{Name: PipelineSize, Help: "Number of commands in redis pipelines.", Kind: Histogram, Buckets: DefaultSizeBuckets},
+		{Name: PipelineDuration, Help: "Duration of redis pipelines in seconds.", Kind: Histogram, Buckets: h.latencyBuckets},
+		{Name: DialErrors, Help: "Number of failed dials.", Kind: Counter, Labels: []string{"addr"}},
+		{Name: BreakerState, Help: "State of the circuit breaker: 0 closed, 1 open, 2 half-open.", Kind: Gauge},
+		{Name: PoolIdleConns, Help: "Number of idle connections.", Kind: Gauge, Labels: []string{"pool"}},
+		{Name: PoolInUseConns, Help: "Number of connections in use.", Kind: Gauge, Labels: []string{"pool"}},
+		{Name: PoolTargetConns, Help: "Maximum number of connections currently allowed.", Kind: Gauge, Labels: []string{"pool"}},
+		{Name: PoolHits, Help: "Number of times an idle connection was reused.", Kind: Counter, Labels: []string{"pool"}},
+		{Name: PoolMisses, Help: "Number of times no idle connection was available.", Kind: Counter, Labels: []string{"pool"}},
+		{Name: PoolWaits, Help: "Number of times a caller waited for a connection.", Kind: Counter, Labels: []string{"pool"}},
+		{Name: PoolWaitDuration, Help: "Total time spent waiting for a connection in seconds.", Kind: Counter, Labels: []string{"pool"}},
+		{Name: PoolTimeouts, Help: "Number of times waiting for a connection timed out.", Kind: Counter, Labels: []string{"pool"}},
+		{Name: PoolStaleConns, Help: "Number of connections closed as stale.", Kind: Counter, Labels: []string{"pool"}},
+	}
+	for _, desc := range descs {
+		registry.Register(desc)
+	}
+	return h
+}
+
+func (h *Hook) AfterProcess(_ context.Context, cmd *rediss.Cmd) {
+	h.registry.Observe(CommandDuration, []string{cmd.Name}, cmd.Duration.Seconds())
+	if code := ErrorCode(cmd.Err); code != "" {
+		h.registry.Add(CommandErrors, []string{cmd.Name, code}, 1)
+	}
+}
+
+func (h *Hook) AfterProcessPipeline(_ context.Context, cmds []*rediss.Cmd) {
+	if len(cmds) == 0 {
+		return
+	}
+	h.registry.Observe(PipelineSize, nil, float64(len(cmds)))
+	h.registry.Observe(PipelineDuration, nil, cmds[0].Duration.Seconds())
+	for _, cmd := range cmds {
+		if code := ErrorCode(cmd.Err); code != "" {
+			h.registry.Add(CommandErrors, []string{cmd.Name, code}, 1)
+		}
+	}
+}
+
+func (h *Hook) AfterDial(_ context.Context, addr string, _ time.Duration, err error) {
+	if err != nil {
+		h.registry.Add(DialErrors, []string{addr}, 1)
+	}
+}
+
+// BreakerStateChanged 记录熔断器的状态
+func (h *Hook) BreakerStateChanged(_, to rediss.BreakerState) {
+	h.registry.Set(BreakerState, nil, float64(to))
+}
+
+// WatchPool 在每次采集指标时读取连接池的统计信息, name用于区分不同的连接池, 如:
+// hook.WatchPool("default", client.PoolStats)
+// hook.WatchPool("blocking", client.BlockingPoolStats)
+// 连接池的统计信息是累计值, 计数器类型的指标通过Add写入与上次采集之间的增量
+func (h *Hook) WatchPool(name string, stats func() *pool.Stats) {
+	var (
+		mu     sync.Mutex
+		last   = make(map[string]float64)
+		labels = []string{name}
+	)
+	add := func(metric string, total float64) {
+		delta := total - last[metric]
+		if delta < 0 {
+			// 统计信息被重置(如阻塞连接池重新创建)
+			delta = total
+		}
+		last[metric] = total
+		if delta > 0 {
+			h.registry.Add(metric, labels, delta)
+		}
+	}
+	h.registry.OnCollect(func() {
+		s := stats()
+		if s == nil {
+			return
+		}
+		h.registry.Set(PoolIdleConns, labels, float64(s.IdleConns))
+		h.registry.Set(PoolInUseConns, labels, float64(s.TotalConns-s.IdleConns))
+		h.registry.Set(PoolTargetConns, labels, float64(s.TargetConns))
+
+		mu.Lock()
+		defer mu.Unlock()
+		add(PoolHits, float64(s.Hits))
+		add(PoolMisses, float64(s.Misses))
+		add(PoolWaits, float64(s.Waits))
+		add(PoolWaitDuration, s.WaitDuration.Seconds())
+		add(PoolTimeouts, float64(s.Timeouts))
+		add(PoolStaleConns, float64(s.StaleConns+s.ExpiredConns))
+	})
+}
+
+// ErrorCode 返回用于统计的错误码, 没有错误时返回空字符串:
+// 服务器返回的错误使用错误信息的第一个单词(如ERR、WRONGTYPE), 其他错误按照来源分类
+func ErrorCode(err error) string {
+	switch err {
+	case nil, rediss.NilReply:
+		return ""
+	case rediss.ErrCircuitOpen:
+		return "circuit_open"
+	case pool.ErrPoolTimeout:
+		return "pool_timeout"
+	case io.EOF, io.ErrUnexpectedEOF:
+		return "network"
+	}
+	switch e := err.(type) {
+	case rediss.RedisError:
+		return e.Code()
+	case net.Error:
+		if e.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+	return "other"
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyihe/rediss"
+	"github.com/pyihe/rediss/pool"
+)
+
+func TestHook(t *testing.T) {
+	r := NewTextRegistry()
+	h := New(r)
+
+	h.AfterProcess(context.Background(), &rediss.Cmd{Name: "get", Duration: 2 * time.Millisecond, Err: rediss.RedisError("WRONGTYPE Operation against a key holding the wrong kind of value")})
+	h.AfterProcess(context.Background(), &rediss.Cmd{Name: "get", Duration: 20 * time.Millisecond, Err: rediss.NilReply})
+	h.AfterProcessPipeline(context.Background(), []*rediss.Cmd{
+		{Name: "set", Duration: time.Millisecond},
+		{Name: "incr", Duration: time.Millisecond, Err: io.EOF},
+	})
+	h.AfterDial(context.Background(), "127.0.0.1:6379", time.Millisecond, io.EOF)
+	rediss.BreakerHook(h).BreakerStateChanged(rediss.BreakerClosed, rediss.BreakerOpen)
+	hits := uint64(3)
+	h.WatchPool("default", func() *pool.Stats {
+		return &pool.Stats{IdleConns: 2, TotalConns: 5, TargetConns: 10, Hits: hits}
+	})
+
+	// 连接池的计数器按照增量累加
+	var b strings.Builder
+	for _, hits = range []uint64{3, 7, 7} {
+		b.Reset()
+		if err := r.Export(&b); err != nil {
+			t.Fatalf("export: %v", err)
+		}
+	}
+	out := b.String()
+	expect := []string{
+		"# TYPE rediss_command_duration_seconds histogram",
+		`rediss_command_duration_seconds_bucket{command="get",le="0.0025"} 1`,
+		`rediss_command_duration_seconds_bucket{command="get",le="+Inf"} 2`,
+		`rediss_command_duration_seconds_count{command="get"} 2`,
+		`rediss_command_errors_total{command="get",code="WRONGTYPE"} 1`,
+		`rediss_command_errors_total{command="incr",code="network"} 1`,
+		`rediss_pipeline_size_bucket{le="2"} 1`,
+		`rediss_dial_errors_total{addr="127.0.0.1:6379"} 1`,
+		"rediss_circuit_breaker_state 1",
+		`rediss_pool_idle_connections{pool="default"} 2`,
+		`rediss_pool_in_use_connections{pool="default"} 3`,
+		`rediss_pool_target_connections{pool="default"} 10`,
+		`rediss_pool_hits_total{pool="default"} 7`,
+	}
+	for _, line := range expect {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("missing %q in output:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `code="nil"`) || strings.Contains(out, `command="set",code=`) {
+		t.Fatalf("unexpected error series:\n%s", out)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		code string
+	}{
+		{nil, ""},
+		{rediss.NilReply, ""},
+		{rediss.RedisError("ERR unknown command"), "ERR"},
+		{rediss.ErrCircuitOpen, "circuit_open"},
+		{pool.ErrPoolTimeout, "pool_timeout"},
+		{io.EOF, "network"},
+	}
+	for _, c := range cases {
+		if code := ErrorCode(c.err); code != c.code {
+			t.Fatalf("%v: expect %q, got %q", c.err, c.code, code)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kind 指标的类型
+type Kind int
+
+const (
+	Counter Kind = iota
+	Gauge
+	Histogram
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Counter:
+		return "counter"
+	case Gauge:
+		return "gauge"
+	case Histogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+// Desc 指标的描述
+type Desc struct {
+	Name    string    // 指标名称
+	Help    string    // 说明
+	Kind    Kind      // 类型
+	Labels  []string  // 标签名称
+	Buckets []float64 // 直方图的桶, 只对Histogram有效, 需要升序排列
+}
+
+// Registry 指标的存储, 可以适配Prometheus等实现
+// 所有方法的labelValues与Desc.Labels一一对应
+type Registry interface {
+	// Register 注册指标, 在使用指标前调用
+	Register(desc *Desc)
+	// Add 累加计数器
+	Add(name string, labelValues []string, delta float64)
+	// Set 设置仪表盘的值
+	Set(name string, labelValues []string, value float64)
+	// Observe 记录直方图的观测值
+	Observe(name string, labelValues []string, value float64)
+	// OnCollect 注册在每次采集指标前调用的函数, 用于更新需要按需读取的指标, 如连接池的统计信息
+	OnCollect(fn func())
+}
+
+type series struct {
+	labelValues []string
+	value       float64  // 计数器或者仪表盘的值
+	counts      []uint64 // 直方图每个桶的计数(不累加)
+	sum         float64  // 直方图观测值的总和
+	count       uint64   // 直方图观测值的数量
+}
+
+type family struct {
+	desc   *Desc
+	series map[string]*series
+}
+
+// TextRegistry 内置的Registry实现, 同时实现了http.Handler, 以Prometheus文本格式输出所有指标
+type TextRegistry struct {
+	mu         sync.Mutex
+	families   map[string]*family
+	collectors []func()
+}
+
+func NewTextRegistry() *TextRegistry {
+	return &TextRegistry{families: make(map[string]*family)}
+}
+
+func (r *TextRegistry) Register(desc *Desc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.families[desc.Name]; !ok {
+		r.families[desc.Name] = &family{desc: desc, series: make(map[string]*series)}
+	}
+}
+
+func (r *TextRegistry) Add(name string, labelValues []string, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s := r.seriesLocked(name, labelValues); s != nil {
+		s.value += delta
+	}
+}
+
+func (r *TextRegistry) Set(name string, labelValues []string, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s := r.seriesLocked(name, labelValues); s != nil {
+		s.value = value
+	}
+}
+
+func (r *TextRegistry) Observe(name string, labelValues []string, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.seriesLocked(name, labelValues)
+	if s == nil {
+		return
+	}
+	buckets := r.families[name].desc.Buckets
+	if s.counts == nil {
+		s.counts = make([]uint64, len(buckets))
+	}
+	if i := sort.SearchFloat64s(buckets, value); i < len(buckets) {
+		s.counts[i]++
+	}
+	s.sum += value
+	s.count++
+}
+
+func (r *TextRegistry) OnCollect(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, fn)
+}
+
+// 获取指标的时间序列, 指标没有注册或者标签数量不匹配时返回nil
+// 调用方需要持有锁
+func (r *TextRegistry) seriesLocked(name string, labelValues []string) *series {
+	f, ok := r.families[name]
+	if !ok || len(labelValues) != len(f.desc.Labels) {
+		return nil
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+// ServeHTTP 以Prometheus文本格式输出所有指标
+func (r *TextRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.Export(w)
+}
+
+// Export 以Prometheus文本格式将所有指标写入w
+func (r *TextRegistry) Export(w io.Writer) error {
+	r.mu.Lock()
+	collectors := r.collectors
+	r.mu.Unlock()
+	for _, fn := range collectors {
+		fn()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		if len(f.series) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, f.desc.Help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, f.desc.Kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			writeSeries(&b, f.desc, f.series[key])
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeSeries(b *strings.Builder, desc *Desc, s *series) {
+	if desc.Kind != Histogram {
+		fmt.Fprintf(b, "%s%s %s\n", desc.Name, formatLabels(desc.Labels, s.labelValues, "", ""), formatFloat(s.value))
+		return
+	}
+	var cumulative uint64
+	for i, upper := range desc.Buckets {
+		if s.counts != nil {
+			cumulative += s.counts[i]
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", desc.Name, formatLabels(desc.Labels, s.labelValues, "le", formatFloat(upper)), cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket%s %d\n", desc.Name, formatLabels(desc.Labels, s.labelValues, "le", "+Inf"), s.count)
+	fmt.Fprintf(b, "%s_sum%s %s\n", desc.Name, formatLabels(desc.Labels, s.labelValues, "", ""), formatFloat(s.sum))
+	fmt.Fprintf(b, "%s_count%s %d\n", desc.Name, formatLabels(desc.Labels, s.labelValues, "", ""), s.count)
+}
+
+// 标签值中需要转义的字符
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// 格式化标签, extraName不为空时追加一个额外的标签(直方图的le)
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i := range names {
+		pairs = append(pairs, names[i]+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"strings"
+
+	"github.com/pyihe/rediss/model/generic"
+)
+
+// SlotRange 哈希槽区间, 包含Start和End
+type SlotRange struct {
+	Start int64
+	End   int64
+}
+
+// ReshardOption 将一段哈希槽从源节点迁移到目标节点的选项
+type ReshardOption struct {
+	Slots     SlotRange             // 需要迁移的哈希槽区间
+	BatchSize int64                 // 每次通过CLUSTER GETKEYSINSLOT获取并迁移的key数量, 默认为100
+	Migrate   generic.MigrateOption // MIGRATE命令的选项, 其中Keys和Destination会被忽略; Host和Port为空时使用目标客户端的地址
+}
+
+/******************************************************************************************/
+
+// Node CLUSTER NODES命令返回的单个节点信息
+type Node struct {
+	ID          string           // 节点ID
+	Addr        string           // 客户端连接地址: ip:port
+	BusPort     string           // 集群总线端口
+	Hostname    string           // 主机名, v7.0.0开始返回
+	Flags       []string         // 节点标志: myself, master, slave, fail?, fail, handshake, noaddr, nofailover, noflags
+	MasterID    string           // 如果节点是副本, 则为主节点的ID, 否则为空
+	PingSent    int64            // 最近一次发送PING的毫秒时间戳, 0表示没有待处理的PING
+	PongRecv    int64            // 最近一次收到PONG的毫秒时间戳
+	ConfigEpoch int64            // 配置纪元
+	LinkState   string           // 集群总线连接状态: connected, disconnected
+	Slots       []SlotRange      // 节点负责的哈希槽
+	Migrating   map[int64]string // 正在迁出的哈希槽, value为目标节点ID
+	Importing   map[int64]string // 正在导入的哈希槽, value为源节点ID
+}
+
+// HasFlag 判断节点是否包含指定的标志
+func (n *Node) HasFlag(flag string) bool {
+	for _, f := range n.Flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMyself 是否是当前连接的节点
+func (n *Node) IsMyself() bool {
+	return n.HasFlag("myself")
+}
+
+// IsMaster 是否是主节点
+func (n *Node) IsMaster() bool {
+	return n.HasFlag("master")
+}
+
+// IsReplica 是否是副本
+func (n *Node) IsReplica() bool {
+	return n.HasFlag("slave")
+}
+
+// Shard CLUSTER SHARDS命令返回的单个分片信息
+type Shard struct {
+	Slots []SlotRange  // 分片负责的哈希槽
+	Nodes []*ShardNode // 分片中的节点
+}
+
+// ShardNode 分片中的单个节点
+type ShardNode struct {
+	ID                string // 节点ID
+	Port              int64  // TCP端口
+	TLSPort           int64  // TLS端口
+	IP                string // IP地址
+	Endpoint          string // 客户端连接时应该使用的地址
+	Hostname          string // 主机名
+	Role              string // master, replica
+	ReplicationOffset int64  // 复制偏移量
+	Health            string // online, failed, loading
+}
+
+// Info CLUSTER INFO命令的回复
+type Info struct {
+	State         string            // 集群状态: ok, fail
+	SlotsAssigned int64             // 已分配的哈希槽数量
+	SlotsOK       int64             // 状态不是FAIL或者PFAIL的哈希槽数量
+	SlotsPFail    int64             // 状态为PFAIL的哈希槽数量
+	SlotsFail     int64             // 状态为FAIL的哈希槽数量
+	KnownNodes    int64             // 集群中已知的节点数量, 包括处于握手状态的节点
+	Size          int64             // 至少负责一个哈希槽的主节点数量
+	CurrentEpoch  int64             // 本地的当前纪元
+	MyEpoch       int64             // 当前节点的配置纪元
+	Fields        map[string]string // 所有字段的原始值
+}
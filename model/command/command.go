@@ -0,0 +1,155 @@
+package command
+
+import "strings"
+
+// ListFilter COMMAND LIST命令的FILTERBY选项, 三种过滤方式只能选择一种, 优先级依次为: Module, ACLCat, Pattern
+type ListFilter struct {
+	Module  string // 只返回指定模块的命令
+	ACLCat  string // 只返回指定ACL分类中的命令
+	Pattern string // 只返回匹配指定模式的命令
+}
+
+/******************************************************************************************/
+
+// Info COMMAND INFO命令返回的单个命令的元数据
+type Info struct {
+	Name          string     // 命令名称, 子命令的格式为: container|subcommand, 统一为小写
+	Arity         int64      // 参数个数, 正数表示固定个数, 负数表示最少个数, 均包含命令名称本身
+	Flags         []string   // 命令标志, 如: readonly, write, blocking, noscript, movablekeys等
+	FirstKey      int64      // 第一个key参数的位置
+	LastKey       int64      // 最后一个key参数的位置, 负数表示从后往前计数
+	Step          int64      // 相邻key参数之间的步长
+	ACLCategories []string   // ACL分类, v6.0.0开始返回
+	Tips          []string   // 命令提示, v7.0.0开始返回
+	KeySpecs      []*KeySpec // key规范, v7.0.0开始返回
+	SubCommands   []*Info    // 子命令, v7.0.0开始返回
+}
+
+// HasFlag 判断命令是否包含指定的标志
+func (info *Info) HasFlag(flag string) bool {
+	for _, f := range info.Flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadOnly 是否是只读命令
+func (info *Info) ReadOnly() bool {
+	return info.HasFlag("readonly")
+}
+
+// Write 是否是写命令
+func (info *Info) Write() bool {
+	return info.HasFlag("write")
+}
+
+// Blocking 是否是可能阻塞的命令
+func (info *Info) Blocking() bool {
+	return info.HasFlag("blocking")
+}
+
+// NoScript 是否禁止在脚本中执行
+func (info *Info) NoScript() bool {
+	return info.HasFlag("noscript")
+}
+
+// MovableKeys key的位置是否不固定, 为true时无法根据FirstKey, LastKey, Step确定key, 需要借助COMMAND GETKEYS
+func (info *Info) MovableKeys() bool {
+	return info.HasFlag("movablekeys")
+}
+
+// Keys 根据FirstKey, LastKey, Step从完整的命令参数(包含命令名称)中提取key
+// 对于MovableKeys的命令, 第二个返回值为false
+func (info *Info) Keys(argv []string) ([]string, bool) {
+	if info.MovableKeys() {
+		return nil, false
+	}
+	if info.FirstKey <= 0 || info.Step <= 0 {
+		return nil, true
+	}
+	last := info.LastKey
+	if last < 0 {
+		last += int64(len(argv))
+	}
+	var keys []string
+	for i := info.FirstKey; i <= last && i < int64(len(argv)); i += info.Step {
+		keys = append(keys, argv[i])
+	}
+	return keys, true
+}
+
+// SubCommand 查找子命令, name为子命令名称, 不区分大小写
+func (info *Info) SubCommand(name string) *Info {
+	full := info.Name + "|" + strings.ToLower(name)
+	for _, sub := range info.SubCommands {
+		if sub.Name == full {
+			return sub
+		}
+	}
+	return nil
+}
+
+// KeySpec 命令的key规范
+type KeySpec struct {
+	Notes       string      // 说明
+	Flags       []string    // key规范标志, 如: RW, RO, OW, RM, access, update, insert, delete等
+	BeginSearch BeginSearch // 第一个key的查找方式
+	FindKeys    FindKeys    // 从第一个key开始查找后续key的方式
+}
+
+// BeginSearch key规范的begin_search部分
+type BeginSearch struct {
+	Type      string // index, keyword, unknown
+	Index     int64  // Type为index时, 第一个key的位置
+	Keyword   string // Type为keyword时, 第一个key之前的关键字
+	StartFrom int64  // Type为keyword时, 开始查找关键字的位置
+}
+
+// FindKeys key规范的find_keys部分
+type FindKeys struct {
+	Type      string // range, keynum, unknown
+	LastKey   int64  // Type为range时, 最后一个key相对于第一个key的位置
+	KeyStep   int64  // key之间的步长
+	Limit     int64  // Type为range且LastKey为-1时, 用于限制key的数量
+	KeyNumIdx int64  // Type为keynum时, 表示key数量的参数相对于begin_search的位置
+	FirstKey  int64  // Type为keynum时, 第一个key相对于begin_search的位置
+}
+
+// Doc COMMAND DOCS命令返回的单个命令的文档
+type Doc struct {
+	Name            string      // 命令名称
+	Summary         string      // 简要说明
+	Since           string      // 从哪个版本开始可用
+	Group           string      // 命令分组
+	Complexity      string      // 时间复杂度
+	Module          string      // 所属模块
+	DocFlags        []string    // 文档标志: deprecated, syscmd
+	DeprecatedSince string      // 从哪个版本开始废弃
+	ReplacedBy      string      // 替代的命令
+	History         []History   // 历史变更
+	Arguments       []*Argument // 参数
+	SubCommands     []*Doc      // 子命令
+}
+
+// History 命令的历史变更
+type History struct {
+	Version     string
+	Description string
+}
+
+// Argument 命令参数的描述
+type Argument struct {
+	Name            string      // 参数名称
+	Type            string      // 参数类型: string, integer, double, key, pattern, unix-time, pure-token, oneof, block
+	DisplayText     string      // 显示文本
+	KeySpecIndex    int64       // Type为key时, 对应的KeySpecs下标
+	Token           string      // 参数前的关键字
+	Summary         string      // 简要说明
+	Since           string      // 从哪个版本开始可用
+	DeprecatedSince string      // 从哪个版本开始废弃
+	Flags           []string    // 参数标志: optional, multiple, multiple_token
+	Value           string      // 参数的值
+	Arguments       []*Argument // Type为oneof或者block时的子参数
+}
@@ -0,0 +1,78 @@
+package sentinel
+
+import "strings"
+
+// Instance 哨兵监控的实例(主节点、副本以及其他哨兵)的公共属性
+type Instance struct {
+	Name                  string            // 名称, 对于主节点为监控时指定的名称, 其他实例为ip:port或者runid
+	IP                    string            // IP地址
+	Port                  string            // 端口
+	RunID                 string            // 运行ID
+	Flags                 []string          // 标志: master, slave, sentinel, s_down, o_down, disconnected等
+	LinkPendingCommands   int64             // 等待回复的命令数量
+	LinkRefcount          int64             // 连接的引用计数
+	LastPingSent          int64             // 距离最近一次发送PING的毫秒数
+	LastOkPingReply       int64             // 距离最近一次收到有效PING回复的毫秒数
+	LastPingReply         int64             // 距离最近一次收到PING回复的毫秒数
+	DownAfterMilliseconds int64             // 判定为主观下线所需的毫秒数
+	Fields                map[string]string // 所有字段的原始值
+}
+
+// HasFlag 判断实例是否包含指定的标志
+func (ins *Instance) HasFlag(flag string) bool {
+	for _, f := range ins.Flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Master SENTINEL MASTER(S)命令返回的主节点信息
+type Master struct {
+	Instance
+	InfoRefresh       int64  // 距离最近一次刷新INFO的毫秒数
+	RoleReported      string // 实例报告的角色
+	RoleReportedTime  int64  // 距离角色报告的毫秒数
+	ConfigEpoch       int64  // 配置纪元
+	NumSlaves         int64  // 副本数量
+	NumOtherSentinels int64  // 其他哨兵的数量
+	Quorum            int64  // 判定客观下线所需的哨兵数量
+	FailoverTimeout   int64  // 故障转移超时时间, 单位毫秒
+	ParallelSyncs     int64  // 故障转移后同时与新主节点同步的副本数量
+}
+
+// Replica SENTINEL REPLICAS命令返回的副本信息
+type Replica struct {
+	Instance
+	InfoRefresh        int64  // 距离最近一次刷新INFO的毫秒数
+	RoleReported       string // 实例报告的角色
+	RoleReportedTime   int64  // 距离角色报告的毫秒数
+	MasterLinkDownTime int64  // 与主节点断开连接的毫秒数
+	MasterLinkStatus   string // 与主节点的连接状态: ok, err
+	MasterHost         string // 主节点地址
+	MasterPort         string // 主节点端口
+	SlavePriority      int64  // 副本优先级
+	SlaveReplOffset    int64  // 复制偏移量
+	ReplicaAnnounced   int64  // 是否被主节点宣告
+}
+
+// Sentinel SENTINEL SENTINELS命令返回的其他哨兵信息
+type Sentinel struct {
+	Instance
+	LastHelloMessage int64  // 距离最近一次收到hello消息的毫秒数
+	VotedLeader      string // 投票选出的领头哨兵
+	VotedLeaderEpoch int64  // 投票的纪元
+}
+
+// InfoCache SENTINEL INFO-CACHE命令返回的单个主节点的INFO缓存
+type InfoCache struct {
+	Name    string           // 主节点名称
+	Entries []InfoCacheEntry // 主节点以及副本的INFO缓存
+}
+
+// InfoCacheEntry 单个实例的INFO缓存
+type InfoCacheEntry struct {
+	Age  int64  // 缓存的时长, 单位毫秒
+	Info string // INFO命令的原始输出, 如果尚未获取则为空
+}
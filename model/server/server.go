@@ -0,0 +1,106 @@
+package server
+
+import "time"
+
+// MemoryStats MEMORY STATS命令的回复
+type MemoryStats struct {
+	PeakAllocated               int64                       // Redis消耗的内存峰值(字节)
+	TotalAllocated              int64                       // Redis分配的内存总字节数
+	StartupAllocated            int64                       // Redis启动时消耗的初始内存(字节)
+	ReplicationBacklog          int64                       // 复制积压缓冲区的大小(字节)
+	ClientsSlaves               int64                       // 所有副本的开销(输出和查询缓冲区、连接上下文)
+	ClientsNormal               int64                       // 所有普通客户端的开销(输出和查询缓冲区、连接上下文)
+	ClusterLinks                int64                       // 集群总线连接消耗的内存
+	AOFBuffer                   int64                       // AOF相关缓冲区的总大小
+	LuaCaches                   int64                       // Lua脚本缓存的开销
+	FunctionsCaches             int64                       // Function相关的开销
+	OverheadTotal               int64                       // 所有开销的总和
+	KeysCount                   int64                       // 所有数据库中key的总数
+	KeysBytesPerKey             int64                       // 每个key的平均内存占用
+	DatasetBytes                int64                       // 数据集的大小(字节), 即TotalAllocated减去OverheadTotal
+	DatasetPercentage           float64                     // 数据集占净内存使用量的百分比
+	PeakPercentage              float64                     // TotalAllocated占PeakAllocated的百分比
+	AllocatorAllocated          int64                       // 分配器分配的内存
+	AllocatorActive             int64                       // 分配器活跃页中的内存
+	AllocatorResident           int64                       // 分配器常驻内存
+	AllocatorFragmentationRatio float64                     // 分配器碎片率
+	AllocatorFragmentationBytes int64                       // 分配器碎片字节数
+	AllocatorRSSRatio           float64                     // 分配器RSS比率
+	AllocatorRSSBytes           int64                       // 分配器RSS字节数
+	RSSOverheadRatio            float64                     // RSS开销比率
+	RSSOverheadBytes            int64                       // RSS开销字节数
+	Fragmentation               float64                     // 内存碎片率
+	FragmentationBytes          int64                       // 内存碎片字节数
+	Databases                   map[int64]*DBMemoryOverhead // 每个数据库的开销, key为数据库索引
+	Others                      map[string]string           // 未识别的字段, 用于兼容不同版本的Redis
+}
+
+// DBMemoryOverhead MEMORY STATS命令回复中单个数据库的开销
+type DBMemoryOverhead struct {
+	HashTableMain       int64 // 主字典的开销
+	HashTableExpires    int64 // 过期字典的开销
+	HashTableSlotToKeys int64 // 集群模式下slot到key映射的开销
+}
+
+// MonitorLine MONITOR命令推送的单条记录
+type MonitorLine struct {
+	Time time.Time // 服务器执行命令的时间
+	DB   int64     // 执行命令的数据库索引
+	Addr string    // 客户端地址, 如: 127.0.0.1:6379, unix:/tmp/redis.sock, 对于Lua脚本执行的命令为lua
+	Args []string  // 命令及其参数, 已经去除转义
+	Raw  string    // 解析失败时服务器推送的原始记录
+	Err  error     // 解析失败的原因, 不为nil时只有Raw有效
+}
+
+// FailoverOption FAILOVER命令选项
+type FailoverOption struct {
+	Host    string // TO选项, 指定提升为主节点的副本地址
+	Port    string // TO选项, 指定提升为主节点的副本端口
+	Force   bool   // 超时后即使副本没有追上主节点的偏移量也强制故障转移, 需要同时指定TO和TIMEOUT
+	Abort   bool   // 中止正在进行的故障转移
+	Timeout int64  // 超时时间, 单位毫秒
+}
+
+// ShutdownOption SHUTDOWN命令选项
+type ShutdownOption struct {
+	SaveMode string // NOSAVE, SAVE
+	Now      bool   // 不等待滞后的副本
+	Force    bool   // 忽略保存RDB或者AOF时的错误
+	Abort    bool   // 取消正在进行的关闭操作
+}
+
+/******************************************************************************************/
+
+// Role ROLE命令的回复, 根据Role字段的值, Master, Replica, Sentinel中只有一个不为nil
+type Role struct {
+	Role     string        // master, slave, sentinel
+	Master   *MasterRole   // 主节点的信息
+	Replica  *ReplicaRole  // 副本的信息
+	Sentinel *SentinelRole // 哨兵的信息
+}
+
+// MasterRole 主节点返回的复制信息
+type MasterRole struct {
+	Offset   int64           // 主节点的复制偏移量
+	Replicas []ReplicaOffset // 已连接的副本
+}
+
+// ReplicaOffset 主节点视角中的副本
+type ReplicaOffset struct {
+	Host   string // 副本地址
+	Port   string // 副本端口
+	Offset int64  // 副本确认的复制偏移量
+}
+
+// ReplicaRole 副本返回的复制信息
+type ReplicaRole struct {
+	MasterHost string // 主节点地址
+	MasterPort string // 主节点端口
+	State      string // 复制状态: connect, connecting, sync, connected
+	Offset     int64  // 副本接收到的数据偏移量, -1表示尚未连接
+}
+
+// SentinelRole 哨兵返回的信息
+type SentinelRole struct {
+	MasterNames []string // 监控的主节点名称
+}
@@ -1,9 +1,12 @@
 package rediss
 
 import (
+	"context"
+	"crypto/tls"
 	"time"
 
 	"github.com/pyihe/go-pkg/serialize"
+	"github.com/pyihe/rediss/pool"
 )
 
 type Option func(client *Client)
@@ -14,6 +17,27 @@ func WithAddress(addr string) Option {
 	}
 }
 
+// WithNetwork 设置网络类型, 默认为tcp, 连接unix socket时设置为unix, 此时地址为socket文件的路径
+func WithNetwork(network string) Option {
+	return func(client *Client) {
+		client.network = network
+	}
+}
+
+// WithDialTimeout 设置拨号的超时时间, 默认由操作系统决定
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(client *Client) {
+		client.dialTimeout = timeout
+	}
+}
+
+// WithTLSConfig 使用TLS连接服务器
+func WithTLSConfig(config *tls.Config) Option {
+	return func(client *Client) {
+		client.tlsConfig = config
+	}
+}
+
 func WithUsername(username string) Option {
 	return func(client *Client) {
 		client.username = username
@@ -26,9 +50,25 @@ func WithPassword(password string) Option {
 	}
 }
 
+// WithCredentialsProvider 设置动态的凭证, 每个新连接握手时调用provider获取用户名和密码, 设置后WithUsername和WithPassword不再生效
+// 凭证轮换后调用Client.RefreshCredentials, 已有的连接将在下次使用前重新认证;
+// 握手时返回WRONGPASS会重新获取凭证再试一次, 命令返回NOAUTH时会重新认证并重新执行命令
+func WithCredentialsProvider(provider CredentialsProvider) Option {
+	return func(client *Client) {
+		client.creds.provider = provider
+	}
+}
+
+// WithClientName 设置连接名称, 连接建立时通过HELLO或者CLIENT SETNAME设置, 可以在CLIENT LIST中看到
+func WithClientName(name string) Option {
+	return func(client *Client) {
+		client.clientName = name
+	}
+}
+
 func WithDatabase(db int) Option {
 	return func(client *Client) {
-		client.database = int32(db)
+		client.database = newDatabase(int32(db))
 	}
 }
 
@@ -56,6 +96,75 @@ func WithMaxIdleTime(idleTime time.Duration) Option {
 	}
 }
 
+// WithKeepAliveInterval 连接闲置超过interval后在后台发送PING, 以发现失效的连接并保持连接活跃, 为负数时不发送
+func WithKeepAliveInterval(interval time.Duration) Option {
+	return func(client *Client) {
+		client.poolConfig.KeepAliveInterval = interval
+	}
+}
+
+// WithBlockingPoolSize 设置阻塞命令(如BLPOP、BZMPOP等)专用连接池的最大连接数, 默认与连接池的最大连接数相同
+func WithBlockingPoolSize(size int) Option {
+	return func(client *Client) {
+		client.blocking.size = size
+	}
+}
+
+// WithConnMaxLifetime 连接的最大生命周期, 超过后连接将被关闭并重建, 用于在故障转移或者负载均衡调整后重新分布连接
+// 每个连接的生命周期会随机减少最多jitter, 避免同时创建的连接同时重建, jitter为0时默认为lifetime的1/10, 为负数时不随机
+func WithConnMaxLifetime(lifetime, jitter time.Duration) Option {
+	return func(client *Client) {
+		client.poolConfig.ConnMaxLifetime = lifetime
+		client.poolConfig.ConnMaxLifetimeJitter = jitter
+	}
+}
+
+// WithPoolFIFO 优先复用闲置最久的连接, 默认优先复用最近使用过的连接
+func WithPoolFIFO() Option {
+	return func(client *Client) {
+		client.poolConfig.FIFO = true
+	}
+}
+
+// WithAdaptivePool 根据等待连接的情况在最小连接数和最大连接数之间自动调整连接池的大小
+// interval为调整周期, 一个周期内的平均等待时长超过waitThreshold时扩容, 没有发生等待时缩容
+func WithAdaptivePool(interval, waitThreshold time.Duration) Option {
+	return func(client *Client) {
+		client.poolConfig.Adaptive = true
+		client.poolConfig.AdaptiveInterval = interval
+		client.poolConfig.AdaptiveWaitThreshold = waitThreshold
+	}
+}
+
+// WithRetryPolicy 设置命令的重试策略, policy为nil时不重试, 默认使用DefaultRetryPolicy
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(client *Client) {
+		client.retryPolicy = policy
+	}
+}
+
+// WithCircuitBreaker 开启熔断器, Redis不可用时请求将直接返回ErrCircuitOpen, 而不是等待拨号重试以及读写超时
+func WithCircuitBreaker(config BreakerConfig) Option {
+	return func(client *Client) {
+		client.breaker = newCircuitBreaker(config)
+		client.breaker.hooks = client.hooks
+	}
+}
+
+// WithHooks 添加命令以及拨号的Hook, 客户端创建后也可以通过AddHook添加
+func WithHooks(hooks ...Hook) Option {
+	return func(client *Client) {
+		client.hooks.add(hooks...)
+	}
+}
+
+// WithPoolTimeout 连接数达到上限时, 等待空闲连接的最长时间, 超时将返回pool.ErrPoolTimeout
+func WithPoolTimeout(timeout time.Duration) Option {
+	return func(client *Client) {
+		client.poolConfig.PoolTimeout = timeout
+	}
+}
+
 func WithRetry(retry int) Option {
 	return func(client *Client) {
 		client.poolConfig.Retry = retry
@@ -73,3 +182,84 @@ func WithMinConnNum(num int) Option {
 		c.poolConfig.MinConnSize = num
 	}
 }
+
+// WithOnConnect 新连接建立后调用, 可用于对连接进行初始化, 返回错误时连接将被关闭
+func WithOnConnect(fn func(conn *pool.RedisConn) error) Option {
+	return func(c *Client) {
+		c.poolConfig.OnConnect = fn
+	}
+}
+
+// WithOnClose 连接池关闭连接后调用
+func WithOnClose(fn func(conn *pool.RedisConn)) Option {
+	return func(c *Client) {
+		c.poolConfig.OnClose = fn
+	}
+}
+
+// WithOnCheckout 从连接池中获取到连接后调用
+func WithOnCheckout(fn func(conn *pool.RedisConn)) Option {
+	return func(c *Client) {
+		c.poolConfig.OnCheckout = fn
+	}
+}
+
+// WithOnCheckin 将连接归还给连接池时调用
+func WithOnCheckin(fn func(conn *pool.RedisConn)) Option {
+	return func(c *Client) {
+		c.poolConfig.OnCheckin = fn
+	}
+}
+
+// CallOption 调用选项, 通过Client.With应用于单次或者一组调用, 不影响原客户端
+type CallOption func(client *Client)
+
+// CallTimeout 设置调用的读写超时时间, 阻塞命令的读超时仍然由命令本身的阻塞时长决定
+func CallTimeout(timeout time.Duration) CallOption {
+	return func(client *Client) {
+		client.writeTimeout = timeout
+		client.readTimeout = timeout
+	}
+}
+
+// CallReadTimeout 设置调用读取回复的超时时间
+func CallReadTimeout(timeout time.Duration) CallOption {
+	return func(client *Client) {
+		client.readTimeout = timeout
+	}
+}
+
+// CallWriteTimeout 设置调用发送请求的超时时间
+func CallWriteTimeout(timeout time.Duration) CallOption {
+	return func(client *Client) {
+		client.writeTimeout = timeout
+	}
+}
+
+// DB 在指定的数据库上执行命令, 连接选择的数据库不一致时会先发送SELECT切换, 对哨兵无效
+func DB(db int) CallOption {
+	return func(client *Client) {
+		client.database = newDatabase(int32(db))
+	}
+}
+
+// CallContext 设置调用的上下文, 上下文将被传递给Hook(如用于关联链路追踪的父span), 并在等待空闲连接时响应取消
+func CallContext(ctx context.Context) CallOption {
+	return func(client *Client) {
+		client.ctx = ctx
+	}
+}
+
+// NoRetry 禁止重试, 包括命令的重试以及获取连接时的拨号重试
+func NoRetry() CallOption {
+	return func(client *Client) {
+		client.noRetry = true
+	}
+}
+
+// RetryUnsafe 允许按照重试策略重试非幂等的命令(如INCR、LPUSH), 调用方需要自行确保重复执行是可以接受的
+func RetryUnsafe() CallOption {
+	return func(client *Client) {
+		client.retryUnsafe = true
+	}
+}
@@ -3,24 +3,143 @@ package pool
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
+	"sync/atomic"
 	"time"
 )
 
+// 连接ID生成器
+var connID uint64
+
 type RedisConn struct {
+	id           uint64   // 连接ID, 进程内唯一
 	conn         net.Conn // 真实连接
 	writer       *bufio.Writer
 	reader       *bufio.Reader
 	lastUsedTime time.Time // 最后一次使用时间
+	createdAt    time.Time // 创建时间
+	expireAt     time.Time // 达到最大生命周期的时间, 为零值时表示不限制
+	err          error     // 最近一次导致连接不可用的错误, 不为nil时连接将在归还时被关闭
+
+	// 连接的会话状态, 在握手时设置, 在连接的整个生命周期内有效
+	db       int    // 当前选择的数据库
+	name     string // 通过CLIENT SETNAME设置的连接名称
+	protocol int    // 使用的RESP协议版本
+	authVer  uint64 // 认证时使用的凭证版本
 }
 
 func newConnection(c net.Conn) *RedisConn {
+	now := time.Now()
 	return &RedisConn{
+		id:           atomic.AddUint64(&connID, 1),
 		conn:         c,
 		writer:       bufio.NewWriter(c),
 		reader:       bufio.NewReader(c),
-		lastUsedTime: time.Now(),
+		lastUsedTime: now,
+		createdAt:    now,
+		protocol:     2,
+	}
+}
+
+// CreatedAt 返回连接的创建时间
+func (rc *RedisConn) CreatedAt() time.Time {
+	return rc.createdAt
+}
+
+// 连接是否已经达到最大生命周期
+func (rc *RedisConn) expired(now time.Time) bool {
+	return !rc.expireAt.IsZero() && now.After(rc.expireAt)
+}
+
+// ID 返回连接ID
+func (rc *RedisConn) ID() uint64 {
+	return rc.id
+}
+
+// LocalAddr 返回连接的本地地址
+func (rc *RedisConn) LocalAddr() net.Addr {
+	return rc.conn.LocalAddr()
+}
+
+// RemoteAddr 返回连接的远端地址
+func (rc *RedisConn) RemoteAddr() net.Addr {
+	return rc.conn.RemoteAddr()
+}
+
+// DB 返回连接当前选择的数据库
+func (rc *RedisConn) DB() int {
+	return rc.db
+}
+
+// SetDB 记录连接当前选择的数据库, 应在SELECT成功后调用
+func (rc *RedisConn) SetDB(db int) {
+	rc.db = db
+}
+
+// Name 返回连接的名称
+func (rc *RedisConn) Name() string {
+	return rc.name
+}
+
+// SetName 记录连接的名称, 应在CLIENT SETNAME成功后调用
+func (rc *RedisConn) SetName(name string) {
+	rc.name = name
+}
+
+// Protocol 返回连接使用的RESP协议版本
+func (rc *RedisConn) Protocol() int {
+	return rc.protocol
+}
+
+// SetProtocol 记录连接使用的RESP协议版本, 应在HELLO成功后调用
+func (rc *RedisConn) SetProtocol(protocol int) {
+	rc.protocol = protocol
+}
+
+// AuthVersion 返回连接认证时使用的凭证版本
+func (rc *RedisConn) AuthVersion() uint64 {
+	return rc.authVer
+}
+
+// SetAuthVersion 记录连接认证时使用的凭证版本, 应在认证成功后调用
+func (rc *RedisConn) SetAuthVersion(version uint64) {
+	rc.authVer = version
+}
+
+// MarkBroken 标记连接已经不可用, 如读取到无法解析的回复时, 连接中可能残留未读取的数据
+// 被标记的连接在归还连接池时将被关闭
+func (rc *RedisConn) MarkBroken(err error) {
+	if rc.err == nil {
+		rc.err = err
+	}
+}
+
+// Err 返回导致连接不可用的错误, 连接可用时返回nil
+func (rc *RedisConn) Err() error {
+	return rc.err
+}
+
+// 检查空闲连接是否可用, 只检查错误标记以及进行一次非阻塞的读探测, 不与服务器交互:
+// 空闲连接上不应该有任何可读的数据, 如果读到数据或者EOF, 说明连接已经被对端关闭或者状态异常
+func (rc *RedisConn) healthy() bool {
+	if rc.err != nil || rc.reader.Buffered() > 0 || rc.writer.Buffered() > 0 {
+		return false
+	}
+	if err := rc.conn.SetReadDeadline(time.Now()); err != nil {
+		return false
+	}
+	var b [1]byte
+	_, err := rc.conn.Read(b[:])
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return true
 	}
+	return false
+}
+
+// Close 关闭底层连接
+func (rc *RedisConn) Close() error {
+	return rc.conn.Close()
 }
 
 func (rc *RedisConn) setReadTimeout(timeout time.Duration) (err error) {
@@ -42,6 +161,9 @@ func (rc *RedisConn) setWriteTimeout(timeout time.Duration) (err error) {
 }
 
 func (rc *RedisConn) WriteBytes(b []byte, timeout time.Duration) (n int, err error) {
+	defer func() {
+		rc.MarkBroken(err)
+	}()
 	if err = rc.setWriteTimeout(timeout); err != nil {
 		return
 	}
@@ -53,14 +175,21 @@ func (rc *RedisConn) WriteBytes(b []byte, timeout time.Duration) (n int, err err
 	return
 }
 
+// Read 读取len(p)个字节, 直到读满或者发生错误
 func (rc *RedisConn) Read(p []byte, timeout time.Duration) (n int, err error) {
+	defer func() {
+		rc.MarkBroken(err)
+	}()
 	if err = rc.setReadTimeout(timeout); err != nil {
 		return
 	}
-	return rc.reader.Read(p)
+	return io.ReadFull(rc.reader, p)
 }
 
 func (rc *RedisConn) ReadLine(timeout time.Duration) (line []byte, err error) {
+	defer func() {
+		rc.MarkBroken(err)
+	}()
 	if err = rc.setReadTimeout(timeout); err != nil {
 		return
 	}
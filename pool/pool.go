@@ -2,8 +2,10 @@ package pool
 
 import (
 	"context"
+	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pyihe/go-pkg/backoff"
@@ -14,86 +16,181 @@ import (
 var (
 	ErrUninitializedPool = errors.New("uninitialized pool")
 	ErrAlreadyClosedPool = errors.New("pool closed")
+	ErrPoolTimeout       = errors.New("connection pool timeout")
+	ErrUnusedData        = errors.New("buffer has unused data")
+	ErrNilConfig         = errors.New("nil config")
+	ErrNilDialer         = errors.New("nil dialer")
 )
 
 var (
 	defaultIdleDuration = 10 * time.Second
+	defaultPoolTimeout  = 3 * time.Second
 	defaultMaxConnSize  = 16
 	defaultMinConnSize  = 4
+
+	defaultKeepAliveInterval = 30 * time.Second
+	defaultAdaptiveInterval  = time.Second
 )
 
 type Config struct {
 	Dialer      func() (net.Conn, error) // 拨号
 	MaxIdleTime time.Duration            // 连接最大闲置时长
+	PoolTimeout time.Duration            // 连接数达到上限时, 等待其他调用方归还连接的最长时间
 	Retry       int                      // 拨号失败后的重试次数
-	MaxConnSize int                      // 最大连接数
+	MaxConnSize int                      // 最大连接数, 包括空闲的连接和正在使用的连接
 	MinConnSize int                      // 最小连接数
+
+	KeepAlive         func(conn *RedisConn) error // 对闲置的连接发送心跳, 如PING, 返回错误时连接将被关闭
+	KeepAliveInterval time.Duration               // 连接闲置超过该时长后在后台发送心跳, 为负数时不发送心跳
+
+	ConnMaxLifetime       time.Duration // 连接的最大生命周期, 超过后连接将被关闭并重建, 为0时不限制
+	ConnMaxLifetimeJitter time.Duration // 最大生命周期的随机减少量, 避免同时创建的连接同时重建, 为0时默认为 ConnMaxLifetime 的1/10, 为负数时不随机
+	FIFO                  bool          // 复用空闲连接的顺序, 为true时先进先出, 默认为后进先出
+
+	Adaptive              bool          // 是否根据等待情况在 MinConnSize 和 MaxConnSize 之间自动调整允许打开的连接数
+	AdaptiveInterval      time.Duration // 自动调整的周期
+	AdaptiveWaitThreshold time.Duration // 一个周期内的平均等待时长超过该值时扩容, 默认只要发生等待就扩容
+
+	OnConnect  func(conn *RedisConn) error // 新连接建立后调用, 可用于初始化连接, 返回错误时连接将被关闭
+	OnClose    func(conn *RedisConn)       // 连接池关闭连接后调用
+	OnCheckout func(conn *RedisConn)       // 调用方从连接池中获取到连接后调用
+	OnCheckin  func(conn *RedisConn)       // 调用方将连接归还给连接池时调用
 }
 
 type Pool struct {
+	stats stats // 统计信息
+
 	initialized bool               // 是否已经初始化
 	closed      bool               // 是否已关闭
 	stop        context.CancelFunc // 取消信号量
 	config      *Config            // 连接池的配置
 
-	mu    sync.Mutex // 读取连接队列的锁
-	conns *queue     // 连接
+	mu      sync.Mutex        // 读取连接队列的锁
+	conns   *queue            // 空闲连接
+	numOpen int               // 已经打开的连接数, 包括空闲的连接和正在使用的连接
+	target  int               // 开启 Adaptive 时允许打开的最大连接数
+	waiters []chan *RedisConn // 等待获取连接的调用方, 按照先进先出的顺序获得连接
 }
 
-func New(cfg *Config) *Pool {
+// New 创建连接池, 创建时不会拨号, 而是在后台预热 MinConnSize 个连接,
+// 所以即使Redis暂时不可用, 连接池也能创建成功, 此时获取连接将返回拨号错误
+func New(cfg *Config) (*Pool, error) {
 	if cfg == nil {
-		panic("nil config")
+		return nil, ErrNilConfig
 	}
 	p := &Pool{
 		config: cfg,
 	}
-	p.init()
-	return p
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
-func (p *Pool) init() {
+func (p *Pool) init() error {
 	if p.config.Dialer == nil {
-		panic("nil dialer")
+		return ErrNilDialer
 	}
 	if p.config.MaxIdleTime <= 0 {
 		p.config.MaxIdleTime = defaultIdleDuration
 	}
+	if p.config.PoolTimeout <= 0 {
+		p.config.PoolTimeout = defaultPoolTimeout
+	}
 	if p.config.MaxConnSize <= 0 {
 		p.config.MaxConnSize = defaultMaxConnSize
 	}
 	if p.config.MinConnSize <= 0 {
 		p.config.MinConnSize = defaultMinConnSize
 	}
+	if p.config.MinConnSize > p.config.MaxConnSize {
+		p.config.MinConnSize = p.config.MaxConnSize
+	}
+	if p.config.KeepAliveInterval == 0 {
+		p.config.KeepAliveInterval = defaultKeepAliveInterval
+	}
+	if p.config.ConnMaxLifetimeJitter == 0 || p.config.ConnMaxLifetimeJitter > p.config.ConnMaxLifetime {
+		p.config.ConnMaxLifetimeJitter = p.config.ConnMaxLifetime / 10
+	}
+	if p.config.AdaptiveInterval <= 0 {
+		p.config.AdaptiveInterval = defaultAdaptiveInterval
+	}
+	p.target = p.config.MinConnSize
 	p.config.Retry = maths.MaxInt(p.config.Retry, 0)
 
 	// 初始化连接池中的连接队列
 	p.conns = newQueue(p)
 
-	// 初始化连接
-	for i := 0; i < p.config.MaxConnSize; i++ {
-		c, err := p.dialConn()
-		if err != nil {
-			panic(err)
-		}
-		_ = p.addConn(c)
-	}
-
 	var ctx context.Context
 	ctx, p.stop = context.WithCancel(context.Background())
-	go p.periodicClean(ctx)
 	p.initialized = true
-}
 
-func (p *Pool) addConn(c net.Conn) (err error) {
+	// 在后台预热连接
 	p.mu.Lock()
-	err = p.conns.insert(newConnection(c))
+	need := p.reserveMinConnsLocked()
 	p.mu.Unlock()
-	return
+	go p.fillConns(need)
+	go p.periodicClean(ctx)
+	if p.config.KeepAlive != nil && p.config.KeepAliveInterval > 0 {
+		go p.keepAlive(ctx)
+	}
+	if p.config.Adaptive {
+		go p.adapt(ctx)
+	}
+	return nil
+}
+
+// 当前允许打开的最大连接数
+// 调用方需要持有锁
+func (p *Pool) maxOpenLocked() int {
+	if p.config.Adaptive {
+		return p.target
+	}
+	return p.config.MaxConnSize
 }
 
-func (p *Pool) dialConn() (c net.Conn, err error) {
-	c, err = p.config.Dialer()
-	if err != nil && p.config.Retry > 0 {
+// 计算达到 MinConnSize 个空闲连接还需要新建的连接数, 并提前占用相应的名额, 但不能超过 MaxConnSize
+// 调用方需要持有锁
+func (p *Pool) reserveMinConnsLocked() int {
+	need := p.config.MinConnSize - p.conns.len()
+	if left := p.maxOpenLocked() - p.numOpen; need > left {
+		need = left
+	}
+	need = maths.MaxInt(need, 0)
+	p.numOpen += need
+	return need
+}
+
+type dialRetryKey struct{}
+
+// WithDialRetry 返回一个设置了拨号重试次数的ctx, 使用该ctx获取连接时将使用retry代替 Config.Retry
+func WithDialRetry(ctx context.Context, retry int) context.Context {
+	return context.WithValue(ctx, dialRetryKey{}, retry)
+}
+
+// 获取连接时的拨号重试次数
+func (p *Pool) dialRetry(ctx context.Context) int {
+	if retry, ok := ctx.Value(dialRetryKey{}).(int); ok {
+		return maths.MaxInt(retry, 0)
+	}
+	return p.config.Retry
+}
+
+// 使用已经占用的名额新建n个连接并放入连接池
+func (p *Pool) fillConns(n int) {
+	for i := 0; i < n; i++ {
+		c, err := p.openConn(p.config.Retry)
+		if err != nil {
+			continue
+		}
+		_ = p.put(c)
+	}
+}
+
+// 拨号, 失败后最多重试retries次
+func (p *Pool) dialConn(retries int) (c net.Conn, err error) {
+	c, err = p.dial()
+	if err != nil && retries > 0 {
 		retry := 0
 		for {
 			delay := backoff.Get(nil, retry)
@@ -102,13 +199,13 @@ func (p *Pool) dialConn() (c net.Conn, err error) {
 			case <-timer.C:
 				break
 			}
-			c, err = p.config.Dialer()
+			c, err = p.dial()
 			if err == nil {
 				timer.Stop()
 				break
 			}
 			retry++
-			if retry == p.config.Retry {
+			if retry == retries {
 				timer.Stop()
 				break
 			}
@@ -117,11 +214,109 @@ func (p *Pool) dialConn() (c net.Conn, err error) {
 	return
 }
 
+func (p *Pool) dial() (net.Conn, error) {
+	atomic.AddUint64(&p.stats.dials, 1)
+	c, err := p.config.Dialer()
+	if err != nil {
+		atomic.AddUint64(&p.stats.dialErrors, 1)
+	}
+	return c, err
+}
+
+// 拨号并创建连接, 连接创建后调用 OnConnect
+func (p *Pool) newConn(retries int) (*RedisConn, error) {
+	c, err := p.dialConn(retries)
+	if err != nil {
+		return nil, err
+	}
+	conn := newConnection(c)
+	if lifetime := p.config.ConnMaxLifetime; lifetime > 0 {
+		if jitter := p.config.ConnMaxLifetimeJitter; jitter > 0 {
+			lifetime -= time.Duration(rand.Int63n(int64(jitter)))
+		}
+		conn.expireAt = conn.createdAt.Add(lifetime)
+	}
+	if p.config.OnConnect != nil {
+		if err = p.config.OnConnect(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// 关闭连接并调用 OnClose
+func (p *Pool) closeConn(conn *RedisConn) {
+	_ = conn.Close()
+	if p.config.OnClose != nil {
+		p.config.OnClose(conn)
+	}
+}
+
+// 使用已经占用的连接名额创建连接, 失败时释放名额
+func (p *Pool) openConn(retries int) (*RedisConn, error) {
+	c, err := p.newConn(retries)
+	if err != nil {
+		p.mu.Lock()
+		p.releaseSlotLocked()
+		p.mu.Unlock()
+		return nil, err
+	}
+	return c, nil
+}
+
+// 释放一个连接名额, 如果有调用方在等待, 则将名额直接转交给等待最久的调用方, 由其自行拨号
+// 调用方需要持有锁
+func (p *Pool) releaseSlotLocked() {
+	if w := p.popWaiterLocked(); w != nil {
+		w <- nil
+		return
+	}
+	p.numOpen--
+}
+
+func (p *Pool) popWaiterLocked() chan *RedisConn {
+	if len(p.waiters) == 0 {
+		return nil
+	}
+	w := p.waiters[0]
+	p.waiters[0] = nil
+	p.waiters = p.waiters[1:]
+	return w
+}
+
+// 从等待队列中移除w, 如果w已经不在队列中(已经被分配了连接或名额)则返回false
+func (p *Pool) removeWaiterLocked(w chan *RedisConn) bool {
+	for i := range p.waiters {
+		if p.waiters[i] == w {
+			copy(p.waiters[i:], p.waiters[i+1:])
+			p.waiters[len(p.waiters)-1] = nil
+			p.waiters = p.waiters[:len(p.waiters)-1]
+			return true
+		}
+	}
+	return false
+}
+
+// Dial 拨号一个不受连接池管理的连接, 用于MONITOR、SUBSCRIBE等独占连接的场景
+// 调用方负责关闭该连接, 且不能将其Put回连接池
+func (p *Pool) Dial() (*RedisConn, error) {
+	if !p.initialized {
+		return nil, ErrUninitializedPool
+	}
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil, ErrAlreadyClosedPool
+	}
+	return p.newConn(p.config.Retry)
+}
+
 // 周期性的清理闲置的连接
 // 清理周期为最大闲置时长 MaxIdleTime
 // 凡是闲置时间超过 MaxIdleTime 的都进行清理
 func (p *Pool) periodicClean(ctx context.Context) {
-	var expiredConns []*RedisConn
 	var ticker = time.NewTicker(p.config.MaxIdleTime)
 
 	defer ticker.Stop()
@@ -132,85 +327,367 @@ func (p *Pool) periodicClean(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		}
+
+		p.mu.Lock()
 		if p.closed {
+			p.mu.Unlock()
 			return
 		}
 		// 找到所有已经过期的连接
-		p.mu.Lock()
-		expiredConns = p.conns.searchExpiredConns(p.config.MaxIdleTime)
-		p.checkMinConns(&expiredConns)
+		expiredConns := p.conns.searchExpiredConns(p.config.MaxIdleTime)
+		// 保证空闲连接数不少于 MinConnSize, 优先保留过期的连接
+		for len(expiredConns) > 0 && p.conns.len() < p.config.MinConnSize {
+			last := len(expiredConns) - 1
+			p.conns.insert(expiredConns[last])
+			expiredConns[last] = nil
+			expiredConns = expiredConns[:last]
+		}
+		// 达到最大生命周期的连接无论如何都需要关闭
+		lifetimeConns := p.conns.removeExpired(time.Now())
+		for i := 0; i < len(expiredConns)+len(lifetimeConns); i++ {
+			p.releaseSlotLocked()
+		}
+		// 关闭连接后空闲连接不足时需要拨号补充
+		need := p.reserveMinConnsLocked()
 		p.mu.Unlock()
 
 		// 关闭已经过期的连接
+		atomic.AddUint64(&p.stats.staleConns, uint64(len(expiredConns)))
+		atomic.AddUint64(&p.stats.expiredConns, uint64(len(lifetimeConns)))
 		for i := range expiredConns {
-			//fmt.Printf("断开连接: %v, %v\n", expiredConns[i].conn.LocalAddr(), expiredConns[i].conn.RemoteAddr())
-			expiredConns[i].conn.Close()
+			p.closeConn(expiredConns[i])
 			expiredConns[i] = nil
 		}
+		for _, c := range lifetimeConns {
+			p.closeConn(c)
+		}
+		p.fillConns(need)
 	}
 }
 
-func (p *Pool) checkMinConns(expiredConns *[]*RedisConn) {
-	if p.conns.len() >= p.config.MinConnSize {
-		return
+// 周期性的根据等待情况调整允许打开的最大连接数:
+// 1. 周期内的平均等待时长超过 AdaptiveWaitThreshold, 或者仍有调用方在等待时扩容, 新增的名额直接分配给等待的调用方
+// 2. 周期内没有发生等待并且存在空闲连接时缩容, 每次减少空闲连接数的一半, 多余的空闲连接将被关闭
+func (p *Pool) adapt(ctx context.Context) {
+	var (
+		ticker       = time.NewTicker(p.config.AdaptiveInterval)
+		lastWaits    uint64
+		lastDuration int64
+	)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		waits, duration := atomic.LoadUint64(&p.stats.waits), atomic.LoadInt64(&p.stats.waitDuration)
+		waitDelta, durationDelta := int(waits-lastWaits), time.Duration(duration-lastDuration)
+		lastWaits, lastDuration = waits, duration
+
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		var surplus []*RedisConn
+		idle, waiting := p.conns.len(), len(p.waiters)
+		switch {
+		case waiting > 0 || (waitDelta > 0 && durationDelta/time.Duration(waitDelta) > p.config.AdaptiveWaitThreshold):
+			p.target += maths.MaxInt(waitDelta, waiting)
+			if p.target > p.config.MaxConnSize {
+				p.target = p.config.MaxConnSize
+			}
+			for p.numOpen < p.target {
+				w := p.popWaiterLocked()
+				if w == nil {
+					break
+				}
+				p.numOpen++
+				w <- nil
+			}
+		case waitDelta == 0 && idle > 0:
+			p.target -= maths.MaxInt(idle/2, 1)
+			if p.target < p.config.MinConnSize {
+				p.target = p.config.MinConnSize
+			}
+			if n := p.numOpen - p.target; n > 0 {
+				surplus = p.conns.shift(n)
+				p.numOpen -= len(surplus)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, c := range surplus {
+			p.closeConn(c)
+		}
 	}
-	for p.conns.len() < p.config.MinConnSize {
-		if expireCount := len(*expiredConns); expireCount > 0 {
-			_ = p.conns.insert((*expiredConns)[expireCount-1])
-			(*expiredConns)[expireCount-1] = nil
-			*expiredConns = (*expiredConns)[:expireCount-1]
-		} else {
-			c, err := p.dialConn()
-			if err != nil {
-				return
+}
+
+// 周期性的对闲置时间超过 KeepAliveInterval 的连接发送心跳, 避免连接因为长时间闲置被服务器或者网络设备断开
+// 心跳期间连接不在空闲队列中, 心跳失败的连接将被关闭
+func (p *Pool) keepAlive(ctx context.Context) {
+	var ticker = time.NewTicker(p.config.KeepAliveInterval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		conns := p.conns.takeIdle(p.config.KeepAliveInterval)
+		p.mu.Unlock()
+
+		alive := conns[:0]
+		for _, c := range conns {
+			if err := p.config.KeepAlive(c); err != nil || c.err != nil {
+				atomic.AddUint64(&p.stats.staleConns, 1)
+				p.Remove(c)
+				continue
 			}
-			_ = p.addConn(c)
+			alive = append(alive, c)
+		}
+
+		p.mu.Lock()
+		if p.closed {
+			p.numOpen -= len(alive)
+			p.mu.Unlock()
+			for _, c := range alive {
+				p.closeConn(c)
+			}
+			return
 		}
+		// 心跳期间可能有调用方在排队等待, 优先将连接交给它们
+		for len(alive) > 0 {
+			w := p.popWaiterLocked()
+			if w == nil {
+				break
+			}
+			alive[0].lastUsedTime = time.Now()
+			w <- alive[0]
+			alive = alive[1:]
+		}
+		p.conns.insertFront(alive)
+		p.mu.Unlock()
 	}
 }
 
-func (p *Pool) Get(check func(conn *RedisConn) error) (c *RedisConn, err error) {
+// Get 从连接池中获取一个连接, 空闲连接在返回前会进行非阻塞的可用性探测, 不可用的连接将被关闭并替换
+// 如果没有空闲连接:
+// 1. 连接数没有达到 MaxConnSize 时拨号新建连接
+// 2. 否则排队等待其他调用方归还连接, 直到超过 PoolTimeout(返回ErrPoolTimeout)或者ctx结束
+// check不为nil时将在返回前对连接进行检查, 检查失败的连接将被关闭
+func (p *Pool) Get(ctx context.Context, check func(conn *RedisConn) error) (c *RedisConn, err error) {
 	if !p.initialized {
 		return nil, ErrUninitializedPool
 	}
-	if p.closed {
-		return nil, ErrAlreadyClosedPool
+	if c, _, err = p.get(ctx); err != nil {
+		return nil, err
 	}
+	if check != nil {
+		if err = check(c); err != nil {
+			atomic.AddUint64(&p.stats.staleConns, 1)
+			p.Remove(c)
+			return nil, err
+		}
+	}
+	if p.config.OnCheckout != nil {
+		p.config.OnCheckout(c)
+	}
+	return
+}
+
+func (p *Pool) get(ctx context.Context) (c *RedisConn, idle bool, err error) {
 	p.mu.Lock()
-	c = p.conns.pop()
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, false, ErrAlreadyClosedPool
+		}
+		if c = p.conns.pop(); c == nil {
+			break
+		}
+		p.mu.Unlock()
+		if c.expired(time.Now()) {
+			// 连接已经达到最大生命周期, 关闭后继续获取下一个
+			atomic.AddUint64(&p.stats.expiredConns, 1)
+			p.Remove(c)
+		} else if c.healthy() {
+			atomic.AddUint64(&p.stats.hits, 1)
+			return c, true, nil
+		} else {
+			// 连接已经被对端关闭或者状态异常, 关闭后继续获取下一个
+			atomic.AddUint64(&p.stats.staleConns, 1)
+			p.Remove(c)
+		}
+		p.mu.Lock()
+	}
+	atomic.AddUint64(&p.stats.misses, 1)
+	if p.numOpen < p.maxOpenLocked() {
+		p.numOpen++
+		p.mu.Unlock()
+		c, err = p.openConn(p.dialRetry(ctx))
+		return
+	}
+
+	// 连接数已达上限, 排队等待
+	w := make(chan *RedisConn, 1)
+	p.waiters = append(p.waiters, w)
 	p.mu.Unlock()
 
-	if c == nil {
-		conn, err := p.dialConn()
-		if err != nil {
-			return nil, err
-		}
-		c = newConnection(conn)
-	} else if check != nil {
-		if err = check(c); err != nil {
-			return
+	atomic.AddUint64(&p.stats.waits, 1)
+	start := time.Now()
+	timer := time.NewTimer(p.config.PoolTimeout)
+	defer func() {
+		timer.Stop()
+		atomic.AddInt64(&p.stats.waitDuration, int64(time.Since(start)))
+	}()
+
+	select {
+	case c = <-w:
+		return p.acceptWaited(c, p.dialRetry(ctx))
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+		atomic.AddUint64(&p.stats.timeouts, 1)
+		err = ErrPoolTimeout
+	}
+
+	p.mu.Lock()
+	removed := p.removeWaiterLocked(w)
+	p.mu.Unlock()
+	if !removed {
+		// 超时的同时已经被分配了连接或者名额, 需要归还
+		if c = <-w; c != nil {
+			_ = p.put(c)
+		} else {
+			p.mu.Lock()
+			if !p.closed {
+				p.releaseSlotLocked()
+			}
+			p.mu.Unlock()
 		}
 	}
-	return
+	return nil, false, err
+}
+
+// 处理等待得到的结果: 归还的连接, 或者为nil表示获得了一个连接名额(也可能是连接池已经关闭)
+func (p *Pool) acceptWaited(c *RedisConn, retries int) (*RedisConn, bool, error) {
+	if c != nil {
+		return c, true, nil
+	}
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil, false, ErrAlreadyClosedPool
+	}
+	c, err := p.openConn(retries)
+	return c, false, err
 }
 
+// Put 归还连接, 如果有调用方在等待, 则直接将连接交给等待最久的调用方
 func (p *Pool) Put(conn *RedisConn) error {
 	if !p.initialized {
 		return ErrUninitializedPool
 	}
+	if conn == nil {
+		return nil
+	}
+	if p.config.OnCheckin != nil {
+		p.config.OnCheckin(conn)
+	}
+	return p.put(conn)
+}
+
+func (p *Pool) put(conn *RedisConn) error {
+	// 发生过网络错误或者协议错误的连接不能再使用
+	if conn.err != nil {
+		p.Remove(conn)
+		return nil
+	}
+	// 缓冲区是否有尚未使用的数据
+	if conn.writer.Buffered() > 0 || conn.reader.Buffered() > 0 {
+		p.Remove(conn)
+		return ErrUnusedData
+	}
+	// 达到最大生命周期的连接需要重建
+	if conn.expired(time.Now()) {
+		atomic.AddUint64(&p.stats.expiredConns, 1)
+		p.Remove(conn)
+		return nil
+	}
+
+	p.mu.Lock()
 	if p.closed {
+		p.numOpen--
+		p.mu.Unlock()
+		p.closeConn(conn)
 		return ErrAlreadyClosedPool
 	}
+	if w := p.popWaiterLocked(); w != nil {
+		p.mu.Unlock()
+		conn.lastUsedTime = time.Now()
+		w <- conn
+		return nil
+	}
+	// 缩容后连接数超过了允许的最大值
+	if p.numOpen > p.maxOpenLocked() {
+		p.numOpen--
+		p.mu.Unlock()
+		p.closeConn(conn)
+		return nil
+	}
+	p.conns.insert(conn)
+	p.mu.Unlock()
+	return nil
+}
+
+// Remove 关闭连接并释放其占用的名额, 用于丢弃不再可用的连接
+func (p *Pool) Remove(conn *RedisConn) {
+	if conn == nil {
+		return
+	}
+	p.closeConn(conn)
 	p.mu.Lock()
-	err := p.conns.insert(conn)
+	if p.closed {
+		p.numOpen--
+	} else {
+		p.releaseSlotLocked()
+	}
 	p.mu.Unlock()
-	return err
 }
 
 func (p *Pool) Close() {
-	p.closed = true
-	p.stop()
 	p.mu.Lock()
-	p.conns.reset()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	waiters := p.waiters
+	p.waiters = nil
+	idleConns := p.conns.reset()
+	p.numOpen -= len(idleConns)
 	p.mu.Unlock()
+
+	p.stop()
+	for _, c := range idleConns {
+		p.closeConn(c)
+	}
+	// 唤醒所有等待的调用方, 它们将得到ErrAlreadyClosedPool
+	for _, w := range waiters {
+		w <- nil
+	}
 }
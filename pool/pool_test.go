@@ -1,9 +1,12 @@
 package pool
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -18,7 +21,10 @@ func TestNew(t *testing.T) {
 		MaxConnSize: 64,
 		MinConnSize: 16,
 	}
-	pool := New(cfg)
+	pool, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new pool err: %v", err)
+	}
 	defer pool.Close()
 
 	wg := sync.WaitGroup{}
@@ -26,7 +32,7 @@ func TestNew(t *testing.T) {
 		wg.Add(1)
 		go func(index int, w *sync.WaitGroup) {
 			defer w.Done()
-			c, err := pool.Get(nil)
+			c, err := pool.Get(context.Background(), nil)
 			if err != nil {
 				fmt.Printf("pool get err: %v\n", err)
 				return
@@ -42,3 +48,293 @@ func TestNew(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func newPipeConfig(maxSize int, timeout time.Duration) (*Config, *int32) {
+	var dials int32
+	return &Config{
+		Dialer: func() (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			c, _ := net.Pipe()
+			return c, nil
+		},
+		PoolTimeout: timeout,
+		MaxConnSize: maxSize,
+		MinConnSize: 1,
+	}, &dials
+}
+
+func TestPoolTimeout(t *testing.T) {
+	cfg, dials := newPipeConfig(2, 50*time.Millisecond)
+	p, _ := New(cfg)
+	defer p.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Get(context.Background(), nil); err != nil {
+			t.Fatalf("get err: %v", err)
+		}
+	}
+	start := time.Now()
+	if _, err := p.Get(context.Background(), nil); err != ErrPoolTimeout {
+		t.Fatalf("expect ErrPoolTimeout, got %v", err)
+	}
+	if cost := time.Since(start); cost < 50*time.Millisecond {
+		t.Fatalf("returned before pool timeout: %v", cost)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(ctx, nil); err != context.DeadlineExceeded {
+		t.Fatalf("expect context.DeadlineExceeded, got %v", err)
+	}
+	if n := atomic.LoadInt32(dials); n != 2 {
+		t.Fatalf("expect 2 dials, got %d", n)
+	}
+}
+
+func TestPoolWaitFIFO(t *testing.T) {
+	cfg, dials := newPipeConfig(1, time.Second)
+	p, _ := New(cfg)
+	defer p.Close()
+
+	c, err := p.Get(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("get err: %v", err)
+	}
+
+	order := make(chan int, 3)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			conn, err := p.Get(context.Background(), nil)
+			if err != nil {
+				t.Errorf("waiter %d get err: %v", index, err)
+				return
+			}
+			order <- index
+			_ = p.Put(conn)
+		}(i)
+		// 保证等待者按照顺序排队
+		for {
+			p.mu.Lock()
+			n := len(p.waiters)
+			p.mu.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	_ = p.Put(c)
+	wg.Wait()
+	close(order)
+
+	expect := 0
+	for index := range order {
+		if index != expect {
+			t.Fatalf("expect waiter %d, got %d", expect, index)
+		}
+		expect++
+	}
+	if n := atomic.LoadInt32(dials); n != 1 {
+		t.Fatalf("expect 1 dial, got %d", n)
+	}
+}
+
+func TestPoolStatsAndHooks(t *testing.T) {
+	cfg, _ := newPipeConfig(2, 20*time.Millisecond)
+	var connects, closes, checkouts, checkins int32
+	cfg.OnConnect = func(conn *RedisConn) error {
+		atomic.AddInt32(&connects, 1)
+		return nil
+	}
+	cfg.OnClose = func(conn *RedisConn) { atomic.AddInt32(&closes, 1) }
+	cfg.OnCheckout = func(conn *RedisConn) { atomic.AddInt32(&checkouts, 1) }
+	cfg.OnCheckin = func(conn *RedisConn) { atomic.AddInt32(&checkins, 1) }
+	p, _ := New(cfg)
+
+	// 等待后台预热 MinConnSize 个连接
+	for p.Stats().IdleConns != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	c1, _ := p.Get(context.Background(), nil)
+	c2, _ := p.Get(context.Background(), nil)
+	if _, err := p.Get(context.Background(), nil); err != ErrPoolTimeout {
+		t.Fatalf("expect ErrPoolTimeout, got %v", err)
+	}
+	_ = p.Put(c1)
+	_ = p.Put(c2)
+
+	stats := p.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Waits != 1 || stats.Timeouts != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.Dials != 2 || stats.TotalConns != 2 || stats.IdleConns != 2 || stats.WaitDuration <= 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	p.Close()
+	if connects != 2 || closes != 2 || checkouts != 2 || checkins != 2 {
+		t.Fatalf("unexpected hook calls: %d %d %d %d", connects, closes, checkouts, checkins)
+	}
+}
+
+func TestNewWithUnreachableServer(t *testing.T) {
+	if _, err := New(&Config{}); err != ErrNilDialer {
+		t.Fatalf("expect ErrNilDialer, got %v", err)
+	}
+
+	dialErr := errors.New("connection refused")
+	p, err := New(&Config{
+		Dialer: func() (net.Conn, error) {
+			return nil, dialErr
+		},
+		MaxConnSize: 2,
+	})
+	if err != nil {
+		t.Fatalf("new pool err: %v", err)
+	}
+	defer p.Close()
+
+	if _, err = p.Get(context.Background(), nil); err != dialErr {
+		t.Fatalf("expect dial err, got %v", err)
+	}
+	// 拨号失败后需要释放名额
+	if stats := p.Stats(); stats.TotalConns > 2 {
+		t.Fatalf("unexpected total conns: %d", stats.TotalConns)
+	}
+}
+
+func TestPoolDiscardBrokenConn(t *testing.T) {
+	cfg, dials := newPipeConfig(1, 20*time.Millisecond)
+	cfg.MinConnSize = 0
+	p, _ := New(cfg)
+	defer p.Close()
+
+	c1, err := p.Get(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("get conn: %v", err)
+	}
+	c1.MarkBroken(errors.New("broken"))
+	_ = p.Put(c1)
+	if stats := p.Stats(); stats.TotalConns != 0 || stats.IdleConns != 0 {
+		t.Fatalf("broken conn should be closed: %+v", stats)
+	}
+
+	// 被关闭的连接占用的名额被释放, 可以重新建立连接
+	c2, err := p.Get(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("get conn: %v", err)
+	}
+	if c2 == c1 || atomic.LoadInt32(dials) != 2 {
+		t.Fatalf("expect a new conn, dials: %d", atomic.LoadInt32(dials))
+	}
+	_ = p.Put(c2)
+}
+
+func TestPoolKeepAlive(t *testing.T) {
+	cfg, _ := newPipeConfig(2, 20*time.Millisecond)
+	var pings int32
+	cfg.KeepAliveInterval = 10 * time.Millisecond
+	cfg.KeepAlive = func(conn *RedisConn) error {
+		// 第一次心跳成功, 之后的心跳失败
+		if atomic.AddInt32(&pings, 1) > 1 {
+			return errors.New("ping failed")
+		}
+		return nil
+	}
+	p, _ := New(cfg)
+	defer p.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&pings) < 2 || p.Stats().TotalConns != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("keepalive not working: pings %d, stats %+v", atomic.LoadInt32(&pings), p.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if p.Stats().StaleConns == 0 {
+		t.Fatalf("expect stale conns: %+v", p.Stats())
+	}
+}
+
+func TestPoolConnMaxLifetime(t *testing.T) {
+	cfg, dials := newPipeConfig(1, 20*time.Millisecond)
+	cfg.MinConnSize = 0
+	cfg.ConnMaxLifetime = 20 * time.Millisecond
+	cfg.ConnMaxLifetimeJitter = -1
+	p, _ := New(cfg)
+	defer p.Close()
+
+	c1, _ := p.Get(context.Background(), nil)
+	_ = p.Put(c1)
+	time.Sleep(30 * time.Millisecond)
+
+	c2, err := p.Get(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("get conn: %v", err)
+	}
+	if c2 == c1 || atomic.LoadInt32(dials) != 2 || p.Stats().ExpiredConns != 1 {
+		t.Fatalf("expired conn should be replaced, dials: %d, stats: %+v", atomic.LoadInt32(dials), p.Stats())
+	}
+	_ = p.Put(c2)
+}
+
+func TestPoolReuseOrder(t *testing.T) {
+	for _, fifo := range []bool{false, true} {
+		cfg, _ := newPipeConfig(2, 20*time.Millisecond)
+		cfg.MinConnSize = 0
+		cfg.FIFO = fifo
+		p, _ := New(cfg)
+
+		c1, _ := p.Get(context.Background(), nil)
+		c2, _ := p.Get(context.Background(), nil)
+		_ = p.Put(c1)
+		_ = p.Put(c2)
+
+		expect := c2
+		if fifo {
+			expect = c1
+		}
+		if c, _ := p.Get(context.Background(), nil); c != expect {
+			t.Fatalf("fifo %v: unexpected conn %d", fifo, c.ID())
+		}
+		p.Close()
+	}
+}
+
+func TestPoolAdaptive(t *testing.T) {
+	cfg, _ := newPipeConfig(4, time.Second)
+	cfg.Adaptive = true
+	cfg.AdaptiveInterval = 10 * time.Millisecond
+	p, _ := New(cfg)
+	defer p.Close()
+
+	for p.Stats().IdleConns != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 初始只允许 MinConnSize 个连接, 第二个调用方需要等待直到扩容
+	c1, _ := p.Get(context.Background(), nil)
+	c2, err := p.Get(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("get conn: %v", err)
+	}
+	if stats := p.Stats(); stats.Waits != 1 || stats.TargetConns < 2 {
+		t.Fatalf("pool should grow: %+v", stats)
+	}
+	_ = p.Put(c1)
+	_ = p.Put(c2)
+
+	// 没有等待时缩容到 MinConnSize
+	deadline := time.Now().Add(time.Second)
+	for stats := p.Stats(); stats.TargetConns != 1 || stats.TotalConns != 1; stats = p.Stats() {
+		if time.Now().After(deadline) {
+			t.Fatalf("pool should shrink: %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -2,8 +2,6 @@ package pool
 
 import (
 	"time"
-
-	"github.com/pyihe/go-pkg/errors"
 )
 
 type queue struct {
@@ -23,36 +21,25 @@ func (q *queue) len() int {
 	return len(q.conns)
 }
 
-func (q *queue) insert(conn *RedisConn) (err error) {
+func (q *queue) insert(conn *RedisConn) {
 	if conn == nil {
 		return
 	}
-	// 缓冲区是否有尚未使用的数据
-	if conn.writer.Buffered() > 0 {
-		return errors.New("buffer has unused data")
-	}
-
 	conn.lastUsedTime = time.Now()
-
-	if length := q.len(); length == q.p.config.MaxConnSize { // 如果队列已经满了, 则用conn替换使用时间最早的连接
-		oldConn := q.conns[0]
-		n := copy(q.conns, q.conns[1:])
-		q.conns[length-1] = nil
-		q.conns = q.conns[:n]
-		_ = oldConn.conn.Close()
-		//fmt.Printf("队列已满, 需要替换旧连接: %v->%v\n", oldConn.conn.LocalAddr(), conn.conn.LocalAddr())
-	}
-
 	q.conns = append(q.conns, conn)
 	//fmt.Printf("insert连接[%v]后: %v\n", conn.conn.LocalAddr(), q.len())
-	return
 }
 
+// 获取一个空闲连接, 默认后进先出, 优先复用最近使用过的连接, 使多余的连接能够闲置过期;
+// 配置为 FIFO 时先进先出, 优先复用闲置最久的连接, 使连接的使用更加均匀
 func (q *queue) pop() *RedisConn {
 	length := q.len()
 	if length == 0 {
 		return nil
 	}
+	if q.p.config.FIFO {
+		return q.shift(1)[0]
+	}
 	c := q.conns[length-1]
 	q.conns[length-1] = nil
 	q.conns = q.conns[:length-1]
@@ -60,6 +47,42 @@ func (q *queue) pop() *RedisConn {
 	return c
 }
 
+// 取出闲置最久的n个连接
+func (q *queue) shift(n int) []*RedisConn {
+	if n > q.len() {
+		n = q.len()
+	}
+	if n <= 0 {
+		return nil
+	}
+	conns := make([]*RedisConn, n)
+	copy(conns, q.conns[:n])
+	m := copy(q.conns, q.conns[n:])
+	for i := m; i < len(q.conns); i++ {
+		q.conns[i] = nil
+	}
+	q.conns = q.conns[:m]
+	return conns
+}
+
+// 取出所有已经达到最大生命周期的连接, 剩余的连接保持原有顺序
+func (q *queue) removeExpired(now time.Time) (expired []*RedisConn) {
+	n := 0
+	for _, c := range q.conns {
+		if c.expired(now) {
+			expired = append(expired, c)
+			continue
+		}
+		q.conns[n] = c
+		n++
+	}
+	for i := n; i < len(q.conns); i++ {
+		q.conns[i] = nil
+	}
+	q.conns = q.conns[:n]
+	return
+}
+
 func (q *queue) searchExpiredConns(expire time.Duration) []*RedisConn {
 	length := q.len()
 	// 没有连接
@@ -100,17 +123,35 @@ func (q *queue) searchExpiredConns(expire time.Duration) []*RedisConn {
 	return q.expired
 }
 
-func (q *queue) reset() {
+// 取出所有闲置时间超过idle的连接, 与searchExpiredConns不同, 这里不考虑 MinConnSize
+func (q *queue) takeIdle(idle time.Duration) []*RedisConn {
+	now, n := time.Now(), 0
+	for n < q.len() && now.Sub(q.conns[n].lastUsedTime) > idle {
+		n++
+	}
+	return q.shift(n)
+}
+
+// 将通过takeIdle取出的连接放回队列头部, 保留其最后使用时间, 以保证队列按照最后使用时间有序
+func (q *queue) insertFront(conns []*RedisConn) {
+	if len(conns) == 0 {
+		return
+	}
+	q.conns = append(conns, q.conns...)
+}
+
+// 清空队列, 返回队列中所有的连接, 由调用方负责关闭
+func (q *queue) reset() []*RedisConn {
+	conns := make([]*RedisConn, len(q.conns))
+	copy(conns, q.conns)
 	for i := range q.conns {
-		_ = q.conns[i].conn.Close()
 		q.conns[i] = nil
 	}
-
 	for i := range q.expired {
-		_ = q.expired[i].conn.Close()
 		q.expired[i] = nil
 	}
 	q.conns = q.conns[:0]
 	q.expired = q.expired[:0]
 	q.p = nil
+	return conns
 }
@@ -0,0 +1,58 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats 连接池的统计信息
+type Stats struct {
+	Hits         uint64        // 直接获取到空闲连接的次数
+	Misses       uint64        // 没有空闲连接, 需要拨号或者等待的次数
+	Timeouts     uint64        // 等待连接超过 PoolTimeout 的次数
+	Waits        uint64        // 因为连接数达到上限而排队等待的次数
+	WaitDuration time.Duration // 排队等待的累计时长
+	Dials        uint64        // 拨号的次数, 包括重试
+	DialErrors   uint64        // 拨号失败的次数
+	StaleConns   uint64        // 因为闲置过期或者检查失败而被关闭的连接数
+	ExpiredConns uint64        // 因为达到 ConnMaxLifetime 而被关闭的连接数
+	TotalConns   int           // 当前打开的连接数, 包括空闲的连接和正在使用的连接
+	IdleConns    int           // 当前空闲的连接数
+	TargetConns  int           // 当前允许打开的最大连接数, 开启 Adaptive 时随等待情况在 MinConnSize 和 MaxConnSize 之间变化
+}
+
+// 连接池内部的计数器, 需要保证64位对齐, 所以作为Pool的第一个字段
+type stats struct {
+	hits         uint64
+	misses       uint64
+	timeouts     uint64
+	waits        uint64
+	waitDuration int64
+	dials        uint64
+	dialErrors   uint64
+	staleConns   uint64
+	expiredConns uint64
+}
+
+// Stats 返回连接池当前的统计信息
+func (p *Pool) Stats() *Stats {
+	s := &Stats{
+		Hits:         atomic.LoadUint64(&p.stats.hits),
+		Misses:       atomic.LoadUint64(&p.stats.misses),
+		Timeouts:     atomic.LoadUint64(&p.stats.timeouts),
+		Waits:        atomic.LoadUint64(&p.stats.waits),
+		WaitDuration: time.Duration(atomic.LoadInt64(&p.stats.waitDuration)),
+		Dials:        atomic.LoadUint64(&p.stats.dials),
+		DialErrors:   atomic.LoadUint64(&p.stats.dialErrors),
+		StaleConns:   atomic.LoadUint64(&p.stats.staleConns),
+		ExpiredConns: atomic.LoadUint64(&p.stats.expiredConns),
+	}
+	p.mu.Lock()
+	s.TotalConns = p.numOpen
+	s.TargetConns = p.maxOpenLocked()
+	if !p.closed {
+		s.IdleConns = p.conns.len()
+	}
+	p.mu.Unlock()
+	return s
+}
@@ -0,0 +1,93 @@
+package rediss
+
+import "strings"
+
+// RedactedArg 脱敏后的参数
+const RedactedArg = "?"
+
+// RedactedArgs 返回隐藏了凭证的参数(包括命令名称), 用于日志、链路追踪等需要输出命令的场景, 不修改cmd.Args
+// 隐藏的凭证包括: AUTH的用户名和密码、HELLO AUTH以及MIGRATE AUTH/AUTH2中的用户名和密码、
+// CONFIG SET中的requirepass/masterauth/masteruser、ACL SETUSER的规则、
+// SENTINEL SET中的auth-pass/auth-user以及SENTINEL CONFIG SET中的sentinel-pass/sentinel-user
+func (cmd *Cmd) RedactedArgs() []string {
+	args := append([]string(nil), cmd.Args...)
+	switch cmd.Name {
+	case "auth":
+		// AUTH [username] password
+		redactFrom(args, 1)
+	case "hello":
+		// HELLO [protover [AUTH username password] [SETNAME clientname]]
+		redactAfter(args, 2, "AUTH", 2)
+	case "migrate":
+		// MIGRATE host port key|"" destination-db timeout [COPY] [REPLACE] [AUTH password | AUTH2 username password] [KEYS key...]
+		for i := 6; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "AUTH":
+				redactRange(args, i+1, 1)
+				i++
+			case "AUTH2":
+				redactRange(args, i+1, 2)
+				i += 2
+			case "KEYS":
+				return args
+			}
+		}
+	case "config":
+		// CONFIG SET parameter value [parameter value ...]
+		if isSubCommand(args, "SET") {
+			redactPairs(args, 2, "requirepass", "masterauth", "masteruser")
+		}
+	case "acl":
+		// ACL SETUSER username [rule...], 规则中可能包含密码
+		if isSubCommand(args, "SETUSER") {
+			redactFrom(args, 3)
+		}
+	case "sentinel":
+		switch {
+		case isSubCommand(args, "SET"):
+			// SENTINEL SET master-name option value [option value ...]
+			redactPairs(args, 3, "auth-pass", "auth-user")
+		case isSubCommand(args, "CONFIG") && len(args) > 2 && strings.EqualFold(args[2], "SET"):
+			// SENTINEL CONFIG SET parameter value [parameter value ...]
+			redactPairs(args, 3, "sentinel-pass", "sentinel-user")
+		}
+	}
+	return args
+}
+
+func isSubCommand(args []string, sub string) bool {
+	return len(args) > 1 && strings.EqualFold(args[1], sub)
+}
+
+func redactFrom(args []string, start int) {
+	redactRange(args, start, len(args))
+}
+
+// 隐藏从start开始的n个参数
+func redactRange(args []string, start, n int) {
+	for i := start; i < start+n && i < len(args); i++ {
+		args[i] = RedactedArg
+	}
+}
+
+// 从start开始查找关键字option, 隐藏其后的n个参数
+func redactAfter(args []string, start int, option string, n int) {
+	for i := start; i < len(args); i++ {
+		if strings.EqualFold(args[i], option) {
+			redactRange(args, i+1, n)
+			i += n
+		}
+	}
+}
+
+// 参数为从start开始的键值对, 隐藏键为names之一的值
+func redactPairs(args []string, start int, names ...string) {
+	for i := start; i+1 < len(args); i += 2 {
+		for _, name := range names {
+			if strings.EqualFold(args[i], name) {
+				args[i+1] = RedactedArg
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package rediss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactedArgs(t *testing.T) {
+	cases := []struct {
+		args string
+		want string
+	}{
+		{"AUTH secret", "AUTH ?"},
+		{"AUTH user secret", "AUTH ? ?"},
+		{"HELLO 2 AUTH user secret SETNAME app", "HELLO 2 AUTH ? ? SETNAME app"},
+		{"MIGRATE host 6379 auth 0 1000 COPY AUTH secret", "MIGRATE host 6379 auth 0 1000 COPY AUTH ?"},
+		{"MIGRATE host 6379 x 0 1000 AUTH2 user secret KEYS auth a", "MIGRATE host 6379 x 0 1000 AUTH2 ? ? KEYS auth a"},
+		{"CONFIG SET requirepass secret maxmemory 1gb masterauth secret", "CONFIG SET requirepass ? maxmemory 1gb masterauth ?"},
+		{"CONFIG GET requirepass", "CONFIG GET requirepass"},
+		{"ACL SETUSER alice on >secret ~*", "ACL SETUSER alice ? ? ?"},
+		{"SENTINEL SET mymaster quorum 2 auth-pass secret", "SENTINEL SET mymaster quorum 2 auth-pass ?"},
+		{"SENTINEL CONFIG SET sentinel-pass secret", "SENTINEL CONFIG SET sentinel-pass ?"},
+		{"SENTINEL CONFIG SET resolve-hostnames yes", "SENTINEL CONFIG SET resolve-hostnames yes"},
+		{"SET auth secret", "SET auth secret"},
+	}
+	for _, c := range cases {
+		args := strings.Fields(c.args)
+		cmd := &Cmd{Name: strings.ToLower(args[0]), Args: args}
+		if got := strings.Join(cmd.RedactedArgs(), " "); got != c.want {
+			t.Fatalf("%s: expect %q, got %q", c.args, c.want, got)
+		}
+		if strings.Join(cmd.Args, " ") != c.args {
+			t.Fatalf("%s: args should not be modified", c.args)
+		}
+	}
+}
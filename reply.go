@@ -3,15 +3,20 @@ package rediss
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/pyihe/go-pkg/bytes"
 	"github.com/pyihe/go-pkg/errors"
 	"github.com/pyihe/go-pkg/serialize"
+	"github.com/pyihe/rediss/model/cluster"
+	"github.com/pyihe/rediss/model/command"
 	"github.com/pyihe/rediss/model/generic"
 	"github.com/pyihe/rediss/model/geo"
 	"github.com/pyihe/rediss/model/hash"
 	"github.com/pyihe/rediss/model/list"
 	"github.com/pyihe/rediss/model/redisstring"
+	"github.com/pyihe/rediss/model/sentinel"
+	"github.com/pyihe/rediss/model/server"
 	"github.com/pyihe/rediss/model/set"
 	"github.com/pyihe/rediss/model/sortedset"
 )
@@ -28,7 +33,7 @@ func newReply(b []byte, err ...string) (reply *Reply) {
 		Value: b,
 	}
 	if len(err) > 0 {
-		reply.Err = errors.New(err[0])
+		reply.Err = RedisError(err[0])
 	}
 	return
 }
@@ -460,6 +465,784 @@ func (reply *Reply) parseLCSResult() (result *redisstring.LCSResult, err error)
 	return
 }
 
+// 解析MEMORY STATS命令的结果
+func (reply *Reply) parseMemoryStats() (result *server.MemoryStats, err error) {
+	array := reply.Array
+	result = &server.MemoryStats{
+		Databases: make(map[int64]*server.DBMemoryOverhead),
+		Others:    make(map[string]string),
+	}
+	for i := 0; i < len(array)-1; i += 2 {
+		field, value := array[i].ValueString(), array[i+1]
+		if strings.HasPrefix(field, "db.") {
+			var db int64
+			if db, err = strconv.ParseInt(field[3:], 10, 64); err != nil {
+				return
+			}
+			overhead := &server.DBMemoryOverhead{}
+			for j := 0; j < len(value.Array)-1; j += 2 {
+				var n int64
+				if n, err = value.Array[j+1].Integer(); err != nil {
+					return
+				}
+				switch value.Array[j].ValueString() {
+				case "overhead.hashtable.main":
+					overhead.HashTableMain = n
+				case "overhead.hashtable.expires":
+					overhead.HashTableExpires = n
+				case "overhead.hashtable.slot-to-keys":
+					overhead.HashTableSlotToKeys = n
+				}
+			}
+			result.Databases[db] = overhead
+			continue
+		}
+
+		var n *int64
+		var f *float64
+		switch field {
+		case "peak.allocated":
+			n = &result.PeakAllocated
+		case "total.allocated":
+			n = &result.TotalAllocated
+		case "startup.allocated":
+			n = &result.StartupAllocated
+		case "replication.backlog":
+			n = &result.ReplicationBacklog
+		case "clients.slaves":
+			n = &result.ClientsSlaves
+		case "clients.normal":
+			n = &result.ClientsNormal
+		case "cluster.links":
+			n = &result.ClusterLinks
+		case "aof.buffer":
+			n = &result.AOFBuffer
+		case "lua.caches":
+			n = &result.LuaCaches
+		case "functions.caches":
+			n = &result.FunctionsCaches
+		case "overhead.total":
+			n = &result.OverheadTotal
+		case "keys.count":
+			n = &result.KeysCount
+		case "keys.bytes-per-key":
+			n = &result.KeysBytesPerKey
+		case "dataset.bytes":
+			n = &result.DatasetBytes
+		case "dataset.percentage":
+			f = &result.DatasetPercentage
+		case "peak.percentage":
+			f = &result.PeakPercentage
+		case "allocator.allocated":
+			n = &result.AllocatorAllocated
+		case "allocator.active":
+			n = &result.AllocatorActive
+		case "allocator.resident":
+			n = &result.AllocatorResident
+		case "allocator-fragmentation.ratio":
+			f = &result.AllocatorFragmentationRatio
+		case "allocator-fragmentation.bytes":
+			n = &result.AllocatorFragmentationBytes
+		case "allocator.rss-ratio":
+			f = &result.AllocatorRSSRatio
+		case "allocator.rss-bytes":
+			n = &result.AllocatorRSSBytes
+		case "rss-overhead.ratio":
+			f = &result.RSSOverheadRatio
+		case "rss-overhead.bytes":
+			n = &result.RSSOverheadBytes
+		case "fragmentation":
+			f = &result.Fragmentation
+		case "fragmentation.bytes":
+			n = &result.FragmentationBytes
+		default:
+			result.Others[field] = value.ValueString()
+		}
+		switch {
+		case n != nil:
+			if *n, err = value.Integer(); err != nil {
+				return
+			}
+		case f != nil:
+			if *f, err = value.Float(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// 将由状态字符串组成的数组解析为[]string
+func (reply *Reply) parseStrings() (result []string) {
+	array := reply.Array
+	result = make([]string, 0, len(array))
+	for _, v := range array {
+		result = append(result, v.ValueString())
+	}
+	return
+}
+
+// 解析COMMAND和COMMAND INFO命令的结果, 不存在的命令对应的位置为nil
+func (reply *Reply) parseCommandInfos() (result []*command.Info, err error) {
+	array := reply.Array
+	result = make([]*command.Info, 0, len(array))
+	for _, v := range array {
+		var info *command.Info
+		// 不存在的命令回复*-1, 解析后为nil
+		if v != nil && len(v.Array) > 0 {
+			if info, err = v.parseCommandInfo(); err != nil {
+				return
+			}
+		}
+		result = append(result, info)
+	}
+	return
+}
+
+// 单个命令的元数据格式:
+// 1) 名称 2) 参数个数 3) 标志 4) 第一个key 5) 最后一个key 6) 步长
+// 7) ACL分类(v6.0.0) 8) 提示(v7.0.0) 9) key规范(v7.0.0) 10) 子命令(v7.0.0)
+func (reply *Reply) parseCommandInfo() (info *command.Info, err error) {
+	array := reply.Array
+	if len(array) < 6 {
+		return nil, errors.New("invalid command info")
+	}
+	info = &command.Info{
+		Name:  strings.ToLower(array[0].ValueString()),
+		Flags: array[2].parseStrings(),
+	}
+	if info.Arity, err = array[1].Integer(); err != nil {
+		return
+	}
+	if info.FirstKey, err = array[3].Integer(); err != nil {
+		return
+	}
+	if info.LastKey, err = array[4].Integer(); err != nil {
+		return
+	}
+	if info.Step, err = array[5].Integer(); err != nil {
+		return
+	}
+	if len(array) > 6 {
+		info.ACLCategories = array[6].parseStrings()
+	}
+	if len(array) > 7 {
+		info.Tips = array[7].parseStrings()
+	}
+	if len(array) > 8 {
+		info.KeySpecs = make([]*command.KeySpec, 0, len(array[8].Array))
+		for _, v := range array[8].Array {
+			var spec *command.KeySpec
+			if spec, err = v.parseKeySpec(); err != nil {
+				return
+			}
+			info.KeySpecs = append(info.KeySpecs, spec)
+		}
+	}
+	if len(array) > 9 {
+		if info.SubCommands, err = array[9].parseCommandInfos(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (reply *Reply) parseKeySpec() (spec *command.KeySpec, err error) {
+	spec = &command.KeySpec{}
+	array := reply.Array
+	for i := 0; i < len(array)-1; i += 2 {
+		value := array[i+1]
+		switch array[i].ValueString() {
+		case "notes":
+			spec.Notes = value.ValueString()
+		case "flags":
+			spec.Flags = value.parseStrings()
+		case "begin_search":
+			err = value.rangeKeySpecSearch(func(typ, field string, v *Reply) (err error) {
+				spec.BeginSearch.Type = typ
+				switch field {
+				case "index":
+					spec.BeginSearch.Index, err = v.Integer()
+				case "keyword":
+					spec.BeginSearch.Keyword = v.ValueString()
+				case "startfrom":
+					spec.BeginSearch.StartFrom, err = v.Integer()
+				}
+				return
+			})
+		case "find_keys":
+			err = value.rangeKeySpecSearch(func(typ, field string, v *Reply) (err error) {
+				spec.FindKeys.Type = typ
+				switch field {
+				case "lastkey":
+					spec.FindKeys.LastKey, err = v.Integer()
+				case "keystep":
+					spec.FindKeys.KeyStep, err = v.Integer()
+				case "limit":
+					spec.FindKeys.Limit, err = v.Integer()
+				case "keynumidx":
+					spec.FindKeys.KeyNumIdx, err = v.Integer()
+				case "firstkey":
+					spec.FindKeys.FirstKey, err = v.Integer()
+				}
+				return
+			})
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// begin_search和find_keys的格式均为: type <type> spec [<field> <value> ...]
+func (reply *Reply) rangeKeySpecSearch(fn func(typ, field string, value *Reply) error) (err error) {
+	var typ string
+	var spec *Reply
+	array := reply.Array
+	for i := 0; i < len(array)-1; i += 2 {
+		switch array[i].ValueString() {
+		case "type":
+			typ = array[i+1].ValueString()
+		case "spec":
+			spec = array[i+1]
+		}
+	}
+	if spec == nil || len(spec.Array) == 0 {
+		return fn(typ, "", nil)
+	}
+	for i := 0; i < len(spec.Array)-1; i += 2 {
+		if err = fn(typ, spec.Array[i].ValueString(), spec.Array[i+1]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 解析COMMAND DOCS命令的结果, 格式为由命令名称和文档交替组成的数组
+func (reply *Reply) parseCommandDocs() (result []*command.Doc, err error) {
+	array := reply.Array
+	result = make([]*command.Doc, 0, len(array)/2)
+	for i := 0; i < len(array)-1; i += 2 {
+		var doc *command.Doc
+		if doc, err = array[i+1].parseCommandDoc(array[i].ValueString()); err != nil {
+			return
+		}
+		result = append(result, doc)
+	}
+	return
+}
+
+func (reply *Reply) parseCommandDoc(name string) (doc *command.Doc, err error) {
+	doc = &command.Doc{Name: name}
+	array := reply.Array
+	for i := 0; i < len(array)-1; i += 2 {
+		value := array[i+1]
+		switch array[i].ValueString() {
+		case "summary":
+			doc.Summary = value.ValueString()
+		case "since":
+			doc.Since = value.ValueString()
+		case "group":
+			doc.Group = value.ValueString()
+		case "complexity":
+			doc.Complexity = value.ValueString()
+		case "module":
+			doc.Module = value.ValueString()
+		case "doc_flags":
+			doc.DocFlags = value.parseStrings()
+		case "deprecated_since":
+			doc.DeprecatedSince = value.ValueString()
+		case "replaced_by":
+			doc.ReplacedBy = value.ValueString()
+		case "history":
+			doc.History = make([]command.History, 0, len(value.Array))
+			for _, h := range value.Array {
+				if len(h.Array) != 2 {
+					continue
+				}
+				doc.History = append(doc.History, command.History{
+					Version:     h.Array[0].ValueString(),
+					Description: h.Array[1].ValueString(),
+				})
+			}
+		case "arguments":
+			if doc.Arguments, err = value.parseCommandArguments(); err != nil {
+				return
+			}
+		case "subcommands":
+			if doc.SubCommands, err = value.parseCommandDocs(); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (reply *Reply) parseCommandArguments() (result []*command.Argument, err error) {
+	result = make([]*command.Argument, 0, len(reply.Array))
+	for _, v := range reply.Array {
+		arg := &command.Argument{}
+		array := v.Array
+		for i := 0; i < len(array)-1; i += 2 {
+			value := array[i+1]
+			switch array[i].ValueString() {
+			case "name":
+				arg.Name = value.ValueString()
+			case "type":
+				arg.Type = value.ValueString()
+			case "display_text":
+				arg.DisplayText = value.ValueString()
+			case "key_spec_index":
+				if arg.KeySpecIndex, err = value.Integer(); err != nil {
+					return
+				}
+			case "token":
+				arg.Token = value.ValueString()
+			case "summary":
+				arg.Summary = value.ValueString()
+			case "since":
+				arg.Since = value.ValueString()
+			case "deprecated_since":
+				arg.DeprecatedSince = value.ValueString()
+			case "flags":
+				arg.Flags = value.parseStrings()
+			case "value":
+				arg.Value = value.ValueString()
+			case "arguments":
+				if arg.Arguments, err = value.parseCommandArguments(); err != nil {
+					return
+				}
+			}
+		}
+		result = append(result, arg)
+	}
+	return
+}
+
+// 解析ROLE命令的结果
+func (reply *Reply) parseRole() (result *server.Role, err error) {
+	array := reply.Array
+	if len(array) == 0 {
+		return nil, errors.New("invalid role reply")
+	}
+	result = &server.Role{Role: array[0].ValueString()}
+	switch result.Role {
+	case "master":
+		// 格式: master <offset> [[ip, port, offset], ...]
+		if len(array) != 3 {
+			return nil, errors.New("invalid role reply")
+		}
+		master := &server.MasterRole{}
+		if master.Offset, err = array[1].Integer(); err != nil {
+			return
+		}
+		master.Replicas = make([]server.ReplicaOffset, 0, len(array[2].Array))
+		for _, v := range array[2].Array {
+			if len(v.Array) != 3 {
+				continue
+			}
+			r := server.ReplicaOffset{
+				Host: v.Array[0].ValueString(),
+				Port: v.Array[1].ValueString(),
+			}
+			if r.Offset, err = v.Array[2].Integer(); err != nil {
+				return
+			}
+			master.Replicas = append(master.Replicas, r)
+		}
+		result.Master = master
+	case "slave":
+		// 格式: slave <master ip> <master port> <state> <offset>
+		if len(array) != 5 {
+			return nil, errors.New("invalid role reply")
+		}
+		replica := &server.ReplicaRole{
+			MasterHost: array[1].ValueString(),
+			MasterPort: array[2].ValueString(),
+			State:      array[3].ValueString(),
+		}
+		if replica.Offset, err = array[4].Integer(); err != nil {
+			return
+		}
+		result.Replica = replica
+	case "sentinel":
+		// 格式: sentinel [master name, ...]
+		if len(array) != 2 {
+			return nil, errors.New("invalid role reply")
+		}
+		result.Sentinel = &server.SentinelRole{MasterNames: array[1].parseStrings()}
+	}
+	return
+}
+
+// 解析CLUSTER NODES命令的结果, 每行描述一个节点, 格式为:
+// <id> <ip:port@cport[,hostname]> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> <slot> ... <slot>
+func (reply *Reply) parseClusterNodes() (result []*cluster.Node, err error) {
+	lines := strings.Split(reply.ValueString(), "\n")
+	result = make([]*cluster.Node, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 8 {
+			return nil, errors.New("invalid cluster node: " + line)
+		}
+		node := &cluster.Node{
+			ID:        fields[0],
+			Flags:     strings.Split(fields[2], ","),
+			LinkState: fields[7],
+		}
+		addr := fields[1]
+		if i := strings.IndexByte(addr, ','); i >= 0 {
+			addr, node.Hostname = addr[:i], addr[i+1:]
+		}
+		if i := strings.IndexByte(addr, '@'); i >= 0 {
+			addr, node.BusPort = addr[:i], addr[i+1:]
+		}
+		node.Addr = addr
+		if fields[3] != "-" {
+			node.MasterID = fields[3]
+		}
+		if node.PingSent, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+			return
+		}
+		if node.PongRecv, err = strconv.ParseInt(fields[5], 10, 64); err != nil {
+			return
+		}
+		if node.ConfigEpoch, err = strconv.ParseInt(fields[6], 10, 64); err != nil {
+			return
+		}
+		for _, slot := range fields[8:] {
+			// 正在迁移的哈希槽: [slot->-node-id]表示迁出, [slot-<-node-id]表示导入
+			if strings.HasPrefix(slot, "[") {
+				slot = strings.Trim(slot, "[]")
+				var m map[int64]string
+				var sep string
+				switch {
+				case strings.Contains(slot, "->-"):
+					sep = "->-"
+					if node.Migrating == nil {
+						node.Migrating = make(map[int64]string)
+					}
+					m = node.Migrating
+				case strings.Contains(slot, "-<-"):
+					sep = "-<-"
+					if node.Importing == nil {
+						node.Importing = make(map[int64]string)
+					}
+					m = node.Importing
+				default:
+					continue
+				}
+				parts := strings.SplitN(slot, sep, 2)
+				var n int64
+				if n, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+					return
+				}
+				m[n] = parts[1]
+				continue
+			}
+			var r cluster.SlotRange
+			start, end := slot, slot
+			if i := strings.IndexByte(slot, '-'); i >= 0 {
+				start, end = slot[:i], slot[i+1:]
+			}
+			if r.Start, err = strconv.ParseInt(start, 10, 64); err != nil {
+				return
+			}
+			if r.End, err = strconv.ParseInt(end, 10, 64); err != nil {
+				return
+			}
+			node.Slots = append(node.Slots, r)
+		}
+		result = append(result, node)
+	}
+	return
+}
+
+// 解析CLUSTER SHARDS命令的结果
+func (reply *Reply) parseClusterShards() (result []*cluster.Shard, err error) {
+	result = make([]*cluster.Shard, 0, len(reply.Array))
+	for _, v := range reply.Array {
+		shard := &cluster.Shard{}
+		array := v.Array
+		for i := 0; i < len(array)-1; i += 2 {
+			value := array[i+1]
+			switch array[i].ValueString() {
+			case "slots":
+				// 格式: [start, end, start, end, ...]
+				shard.Slots = make([]cluster.SlotRange, 0, len(value.Array)/2)
+				for j := 0; j < len(value.Array)-1; j += 2 {
+					var r cluster.SlotRange
+					if r.Start, err = value.Array[j].Integer(); err != nil {
+						return
+					}
+					if r.End, err = value.Array[j+1].Integer(); err != nil {
+						return
+					}
+					shard.Slots = append(shard.Slots, r)
+				}
+			case "nodes":
+				shard.Nodes = make([]*cluster.ShardNode, 0, len(value.Array))
+				for _, n := range value.Array {
+					var node *cluster.ShardNode
+					if node, err = n.parseClusterShardNode(); err != nil {
+						return
+					}
+					shard.Nodes = append(shard.Nodes, node)
+				}
+			}
+		}
+		result = append(result, shard)
+	}
+	return
+}
+
+func (reply *Reply) parseClusterShardNode() (node *cluster.ShardNode, err error) {
+	node = &cluster.ShardNode{}
+	array := reply.Array
+	for i := 0; i < len(array)-1; i += 2 {
+		value := array[i+1]
+		switch array[i].ValueString() {
+		case "id":
+			node.ID = value.ValueString()
+		case "port":
+			node.Port, err = value.Integer()
+		case "tls-port":
+			node.TLSPort, err = value.Integer()
+		case "ip":
+			node.IP = value.ValueString()
+		case "endpoint":
+			node.Endpoint = value.ValueString()
+		case "hostname":
+			node.Hostname = value.ValueString()
+		case "role":
+			node.Role = value.ValueString()
+		case "replication-offset":
+			node.ReplicationOffset, err = value.Integer()
+		case "health":
+			node.Health = value.ValueString()
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 解析CLUSTER INFO命令的结果, 格式为由<field>:<value>组成的多行文本
+func (reply *Reply) parseClusterInfo() (result *cluster.Info, err error) {
+	result = &cluster.Info{Fields: make(map[string]string)}
+	for _, line := range strings.Split(reply.ValueString(), "\r\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		field, value := line[:i], line[i+1:]
+		result.Fields[field] = value
+
+		var n *int64
+		switch field {
+		case "cluster_state":
+			result.State = value
+		case "cluster_slots_assigned":
+			n = &result.SlotsAssigned
+		case "cluster_slots_ok":
+			n = &result.SlotsOK
+		case "cluster_slots_pfail":
+			n = &result.SlotsPFail
+		case "cluster_slots_fail":
+			n = &result.SlotsFail
+		case "cluster_known_nodes":
+			n = &result.KnownNodes
+		case "cluster_size":
+			n = &result.Size
+		case "cluster_current_epoch":
+			n = &result.CurrentEpoch
+		case "cluster_my_epoch":
+			n = &result.MyEpoch
+		}
+		if n != nil {
+			if *n, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// 将由字段名和值交替组成的数组解析为map
+func (reply *Reply) parseFieldMap() (result map[string]string) {
+	array := reply.Array
+	result = make(map[string]string, len(array)/2)
+	for i := 0; i < len(array)-1; i += 2 {
+		result[array[i].ValueString()] = array[i+1].ValueString()
+	}
+	return
+}
+
+// 哨兵返回的整数字段以及解析后的存放位置
+type sentinelInt struct {
+	name string
+	dst  *int64
+}
+
+// 解析哨兵返回的整数字段, 不同版本返回的字段不同, 字段不存在时保持为0, 存在但不是整数时返回错误
+func parseSentinelInts(fields map[string]string, ints ...sentinelInt) error {
+	for _, v := range ints {
+		value, ok := fields[v.name]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid sentinel field %s: %w", v.name, err)
+		}
+		*v.dst = n
+	}
+	return nil
+}
+
+// 解析哨兵返回的实例公共字段, 原始值保存在Fields中
+func parseSentinelInstance(fields map[string]string) (ins sentinel.Instance, err error) {
+	ins = sentinel.Instance{
+		Name:   fields["name"],
+		IP:     fields["ip"],
+		Port:   fields["port"],
+		RunID:  fields["runid"],
+		Fields: fields,
+	}
+	if flags := fields["flags"]; flags != "" {
+		ins.Flags = strings.Split(flags, ",")
+	}
+	err = parseSentinelInts(fields,
+		sentinelInt{"link-pending-commands", &ins.LinkPendingCommands},
+		sentinelInt{"link-refcount", &ins.LinkRefcount},
+		sentinelInt{"last-ping-sent", &ins.LastPingSent},
+		sentinelInt{"last-ok-ping-reply", &ins.LastOkPingReply},
+		sentinelInt{"last-ping-reply", &ins.LastPingReply},
+		sentinelInt{"down-after-milliseconds", &ins.DownAfterMilliseconds},
+	)
+	return
+}
+
+// 解析SENTINEL MASTER命令的结果
+func (reply *Reply) parseSentinelMaster() (result *sentinel.Master, err error) {
+	fields := reply.parseFieldMap()
+	result = &sentinel.Master{RoleReported: fields["role-reported"]}
+	if result.Instance, err = parseSentinelInstance(fields); err != nil {
+		return nil, err
+	}
+	err = parseSentinelInts(fields,
+		sentinelInt{"info-refresh", &result.InfoRefresh},
+		sentinelInt{"role-reported-time", &result.RoleReportedTime},
+		sentinelInt{"config-epoch", &result.ConfigEpoch},
+		sentinelInt{"num-slaves", &result.NumSlaves},
+		sentinelInt{"num-other-sentinels", &result.NumOtherSentinels},
+		sentinelInt{"quorum", &result.Quorum},
+		sentinelInt{"failover-timeout", &result.FailoverTimeout},
+		sentinelInt{"parallel-syncs", &result.ParallelSyncs},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// 解析SENTINEL MASTERS命令的结果
+func (reply *Reply) parseSentinelMasters() (result []*sentinel.Master, err error) {
+	result = make([]*sentinel.Master, 0, len(reply.Array))
+	for _, v := range reply.Array {
+		var master *sentinel.Master
+		if master, err = v.parseSentinelMaster(); err != nil {
+			return nil, err
+		}
+		result = append(result, master)
+	}
+	return
+}
+
+// 解析SENTINEL REPLICAS命令的结果
+func (reply *Reply) parseSentinelReplicas() (result []*sentinel.Replica, err error) {
+	result = make([]*sentinel.Replica, 0, len(reply.Array))
+	for _, v := range reply.Array {
+		fields := v.parseFieldMap()
+		r := &sentinel.Replica{
+			RoleReported:     fields["role-reported"],
+			MasterLinkStatus: fields["master-link-status"],
+			MasterHost:       fields["master-host"],
+			MasterPort:       fields["master-port"],
+		}
+		if r.Instance, err = parseSentinelInstance(fields); err != nil {
+			return nil, err
+		}
+		err = parseSentinelInts(fields,
+			sentinelInt{"info-refresh", &r.InfoRefresh},
+			sentinelInt{"role-reported-time", &r.RoleReportedTime},
+			sentinelInt{"master-link-down-time", &r.MasterLinkDownTime},
+			sentinelInt{"slave-priority", &r.SlavePriority},
+			sentinelInt{"slave-repl-offset", &r.SlaveReplOffset},
+			sentinelInt{"replica-announced", &r.ReplicaAnnounced},
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return
+}
+
+// 解析SENTINEL SENTINELS命令的结果
+func (reply *Reply) parseSentinelSentinels() (result []*sentinel.Sentinel, err error) {
+	result = make([]*sentinel.Sentinel, 0, len(reply.Array))
+	for _, v := range reply.Array {
+		fields := v.parseFieldMap()
+		s := &sentinel.Sentinel{VotedLeader: fields["voted-leader"]}
+		if s.Instance, err = parseSentinelInstance(fields); err != nil {
+			return nil, err
+		}
+		err = parseSentinelInts(fields,
+			sentinelInt{"last-hello-message", &s.LastHelloMessage},
+			sentinelInt{"voted-leader-epoch", &s.VotedLeaderEpoch},
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return
+}
+
+// 解析SENTINEL INFO-CACHE命令的结果, 格式为:
+// [master name, [[age, info], [age, info], ...], master name, [...], ...]
+func (reply *Reply) parseSentinelInfoCache() (result []*sentinel.InfoCache, err error) {
+	array := reply.Array
+	if len(array)%2 != 0 {
+		return nil, errors.New("invalid info-cache reply")
+	}
+	result = make([]*sentinel.InfoCache, 0, len(array)/2)
+	for i := 0; i < len(array); i += 2 {
+		cache := &sentinel.InfoCache{Name: array[i].ValueString()}
+		for _, v := range array[i+1].Array {
+			if len(v.Array) != 2 {
+				continue
+			}
+			var entry sentinel.InfoCacheEntry
+			if entry.Age, err = v.Array[0].Integer(); err != nil {
+				return nil, err
+			}
+			if v.Array[1] != nil {
+				entry.Info = v.Array[1].ValueString()
+			}
+			cache.Entries = append(cache.Entries, entry)
+		}
+		result = append(result, cache)
+	}
+	return
+}
+
 // Just for test
 func (reply *Reply) print(prefix string) {
 	if reply == nil {
@@ -0,0 +1,390 @@
+package rediss
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyihe/rediss/model/cluster"
+	"github.com/pyihe/rediss/model/sentinel"
+	"github.com/pyihe/rediss/model/server"
+	"github.com/pyihe/rediss/pool"
+)
+
+// 状态回复, 用于构造测试数据
+type status string
+
+// 将测试数据编码为RESP: string为Bulk String, status为Simple String, int为Integer, nil为*-1, []interface{}为Array
+func encodeRESP(v interface{}) string {
+	switch data := v.(type) {
+	case nil:
+		return "*-1\r\n"
+	case status:
+		return "+" + string(data) + "\r\n"
+	case string:
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(data), data)
+	case int:
+		return fmt.Sprintf(":%d\r\n", data)
+	case []interface{}:
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(data))
+		for _, e := range data {
+			b.WriteString(encodeRESP(e))
+		}
+		return b.String()
+	}
+	panic(fmt.Sprintf("unsupported type %T", v))
+}
+
+// 通过真实的连接读取并解析回复, 与命令执行时的解析过程一致
+func readTestReply(t *testing.T, v interface{}) *Reply {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	payload := []byte(encodeRESP(v))
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write(payload)
+		}
+	}()
+
+	p, err := pool.New(&pool.Config{
+		Dialer:      func() (net.Conn, error) { return net.Dial("tcp", ln.Addr().String()) },
+		MaxConnSize: 1,
+		MinConnSize: 1,
+	})
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	defer p.Close()
+	conn, err := p.Dial()
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	reply, err := readConn(conn, time.Second)
+	if err != nil {
+		t.Fatalf("read reply: %v", err)
+	}
+	return reply
+}
+
+func TestParseClusterNodes(t *testing.T) {
+	text := "07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004,host-4 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected\n" +
+		"e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001 myself,master - 0 0 1 connected 0-5460 5462 [5461->-292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f] [5463-<-67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1]\n"
+	nodes, err := newReply([]byte(text)).parseClusterNodes()
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expect 2 nodes, got %d", len(nodes))
+	}
+
+	replica := nodes[0]
+	if replica.Addr != "127.0.0.1:30004" || replica.BusPort != "31004" || replica.Hostname != "host-4" {
+		t.Fatalf("unexpected address: %+v", replica)
+	}
+	if !replica.IsReplica() || replica.MasterID != "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca" || replica.PongRecv != 1426238317239 {
+		t.Fatalf("unexpected replica: %+v", replica)
+	}
+
+	master := nodes[1]
+	if !master.IsMyself() || !master.IsMaster() || master.MasterID != "" || master.ConfigEpoch != 1 {
+		t.Fatalf("unexpected master: %+v", master)
+	}
+	if want := []cluster.SlotRange{{Start: 0, End: 5460}, {Start: 5462, End: 5462}}; !reflect.DeepEqual(master.Slots, want) {
+		t.Fatalf("unexpected slots: %+v", master.Slots)
+	}
+	if master.Migrating[5461] != "292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f" || master.Importing[5463] != "67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1" {
+		t.Fatalf("unexpected migrating/importing: %v %v", master.Migrating, master.Importing)
+	}
+}
+
+func TestParseClusterShards(t *testing.T) {
+	// CLUSTER SHARDS, 主节点带有hostname, 副本的health为loading, 未识别的字段被忽略
+	shards, err := readTestReply(t, []interface{}{[]interface{}{
+		"slots", []interface{}{0, 5460, 5462, 5462},
+		"nodes", []interface{}{
+			[]interface{}{
+				"id", "e10b7051d6bf2d5febd39a2be297bbaea6084111", "port", 30001, "ip", "127.0.0.1", "endpoint", "127.0.0.1",
+				"hostname", "host-1", "role", "master", "replication-offset", 72156, "health", "online",
+			},
+			[]interface{}{
+				"id", "1901f5962d865341e81c85f9f596b1e7160c35ce", "port", 30006, "tls-port", 31006, "ip", "127.0.0.1",
+				"endpoint", "127.0.0.1", "role", "replica", "replication-offset", 72156, "health", "loading", "availability-zone", "a",
+			},
+		},
+	}}).parseClusterShards()
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	want := []*cluster.Shard{{
+		Slots: []cluster.SlotRange{{Start: 0, End: 5460}, {Start: 5462, End: 5462}},
+		Nodes: []*cluster.ShardNode{
+			{ID: "e10b7051d6bf2d5febd39a2be297bbaea6084111", Port: 30001, IP: "127.0.0.1", Endpoint: "127.0.0.1",
+				Hostname: "host-1", Role: "master", ReplicationOffset: 72156, Health: "online"},
+			{ID: "1901f5962d865341e81c85f9f596b1e7160c35ce", Port: 30006, TLSPort: 31006, IP: "127.0.0.1",
+				Endpoint: "127.0.0.1", Role: "replica", ReplicationOffset: 72156, Health: "loading"},
+		},
+	}}
+	if !reflect.DeepEqual(shards, want) {
+		t.Fatalf("unexpected shards: %+v", shards)
+	}
+
+	if _, err = readTestReply(t, []interface{}{[]interface{}{"slots", []interface{}{"a", "b"}}}).parseClusterShards(); err == nil {
+		t.Fatalf("expect error for invalid slots")
+	}
+	if _, err = readTestReply(t, []interface{}{[]interface{}{"nodes", []interface{}{[]interface{}{"port", "x"}}}}).parseClusterShards(); err == nil {
+		t.Fatalf("expect error for invalid port")
+	}
+}
+
+func TestParseClusterInfo(t *testing.T) {
+	text := "cluster_state:ok\r\ncluster_slots_assigned:16384\r\ncluster_slots_ok:16383\r\ncluster_slots_pfail:1\r\n" +
+		"cluster_slots_fail:0\r\ncluster_known_nodes:6\r\ncluster_size:3\r\ncluster_current_epoch:6\r\ncluster_my_epoch:2\r\n" +
+		"cluster_stats_messages_sent:1483972\r\n"
+	info, err := newReply([]byte(text)).parseClusterInfo()
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	want := &cluster.Info{State: "ok", SlotsAssigned: 16384, SlotsOK: 16383, SlotsPFail: 1, KnownNodes: 6, Size: 3, CurrentEpoch: 6, MyEpoch: 2}
+	fields := info.Fields
+	info.Fields = nil
+	if !reflect.DeepEqual(info, want) || len(fields) != 10 || fields["cluster_stats_messages_sent"] != "1483972" {
+		t.Fatalf("unexpected info: %+v %v", info, fields)
+	}
+
+	if _, err = newReply([]byte("cluster_size:three\r\n")).parseClusterInfo(); err == nil {
+		t.Fatalf("expect error for invalid cluster_size")
+	}
+}
+
+func TestParseCommandInfos(t *testing.T) {
+	// COMMAND INFO get not-a-command
+	reply := readTestReply(t, []interface{}{
+		[]interface{}{
+			"get", 2, []interface{}{status("readonly"), status("fast")}, 1, 1, 1,
+			[]interface{}{status("@read"), status("@string"), status("@fast")},
+			[]interface{}{},
+			[]interface{}{[]interface{}{
+				"flags", []interface{}{status("RO"), status("access")},
+				"begin_search", []interface{}{"type", "index", "spec", []interface{}{"index", 1}},
+				"find_keys", []interface{}{"type", "range", "spec", []interface{}{"lastkey", 0, "keystep", 1, "limit", 0}},
+			}},
+			[]interface{}{},
+		},
+		nil,
+	})
+	infos, err := reply.parseCommandInfos()
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if len(infos) != 2 || infos[1] != nil {
+		t.Fatalf("unknown command should be nil: %+v", infos)
+	}
+	get := infos[0]
+	if get.Name != "get" || get.Arity != 2 || !get.HasFlag("readonly") || get.FirstKey != 1 || get.Step != 1 {
+		t.Fatalf("unexpected info: %+v", get)
+	}
+	if len(get.ACLCategories) != 3 || len(get.KeySpecs) != 1 {
+		t.Fatalf("unexpected info: %+v", get)
+	}
+	spec := get.KeySpecs[0]
+	if spec.BeginSearch.Type != "index" || spec.BeginSearch.Index != 1 || spec.FindKeys.Type != "range" || spec.FindKeys.KeyStep != 1 {
+		t.Fatalf("unexpected key spec: %+v", spec)
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	// 主节点: 副本的偏移量是Bulk String
+	role, err := readTestReply(t, []interface{}{
+		"master", 3129659,
+		[]interface{}{
+			[]interface{}{"127.0.0.1", "9001", "3129242"},
+			[]interface{}{"127.0.0.1", "9002", "3129543"},
+		},
+	}).parseRole()
+	if err != nil {
+		t.Fatalf("parse master err: %v", err)
+	}
+	want := &server.MasterRole{Offset: 3129659, Replicas: []server.ReplicaOffset{
+		{Host: "127.0.0.1", Port: "9001", Offset: 3129242},
+		{Host: "127.0.0.1", Port: "9002", Offset: 3129543},
+	}}
+	if role.Role != "master" || !reflect.DeepEqual(role.Master, want) || role.Replica != nil || role.Sentinel != nil {
+		t.Fatalf("unexpected master role: %+v", role)
+	}
+
+	role, err = readTestReply(t, []interface{}{"slave", "127.0.0.1", 9000, "connected", 3167038}).parseRole()
+	if err != nil {
+		t.Fatalf("parse replica err: %v", err)
+	}
+	if r := role.Replica; role.Role != "slave" || r == nil || r.MasterHost != "127.0.0.1" || r.MasterPort != "9000" ||
+		r.State != "connected" || r.Offset != 3167038 || role.Master != nil {
+		t.Fatalf("unexpected replica role: %+v", role)
+	}
+
+	role, err = readTestReply(t, []interface{}{"sentinel", []interface{}{"resque-master", "html-fragments-master"}}).parseRole()
+	if err != nil {
+		t.Fatalf("parse sentinel err: %v", err)
+	}
+	if role.Role != "sentinel" || role.Sentinel == nil || !reflect.DeepEqual(role.Sentinel.MasterNames, []string{"resque-master", "html-fragments-master"}) {
+		t.Fatalf("unexpected sentinel role: %+v", role)
+	}
+
+	if _, err = readTestReply(t, []interface{}{"slave", "127.0.0.1", 9000}).parseRole(); err == nil {
+		t.Fatalf("expect error for truncated reply")
+	}
+}
+
+func TestParseMemoryStats(t *testing.T) {
+	// Redis 7.2的MEMORY STATS, RESP2中浮点数为Bulk String, overhead.db.hashtable.lut为未识别的字段
+	stats, err := readTestReply(t, []interface{}{
+		"peak.allocated", 1125632,
+		"total.allocated", 1035640,
+		"startup.allocated", 945416,
+		"replication.backlog", 0,
+		"clients.slaves", 0,
+		"clients.normal", 22272,
+		"cluster.links", 0,
+		"aof.buffer", 0,
+		"lua.caches", 0,
+		"functions.caches", 184,
+		"db.0", []interface{}{"overhead.hashtable.main", 72, "overhead.hashtable.expires", 32},
+		"db.9", []interface{}{"overhead.hashtable.main", 120, "overhead.hashtable.expires", 0},
+		"overhead.db.hashtable.lut", 16,
+		"overhead.total", 968024,
+		"keys.count", 3,
+		"keys.bytes-per-key", 22538,
+		"dataset.bytes", 67616,
+		"dataset.percentage", "74.939796447753906",
+		"peak.percentage", "92.004989624023438",
+		"allocator.allocated", 1186472,
+		"allocator.active", 1478656,
+		"allocator.resident", 4956160,
+		"allocator-fragmentation.ratio", "1.2462643384933472",
+		"allocator-fragmentation.bytes", 292184,
+		"allocator.rss-ratio", "3.3518006801605225",
+		"allocator.rss-bytes", 3477504,
+		"rss-overhead.ratio", "1.0413223505020142",
+		"rss-overhead.bytes", 204800,
+		"fragmentation", "5.0149283409118652",
+		"fragmentation.bytes", 4131064,
+	}).parseMemoryStats()
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	switch {
+	case stats.PeakAllocated != 1125632 || stats.TotalAllocated != 1035640 || stats.FunctionsCaches != 184:
+		t.Fatalf("unexpected allocated: %+v", stats)
+	case stats.KeysCount != 3 || stats.DatasetBytes != 67616 || stats.FragmentationBytes != 4131064:
+		t.Fatalf("unexpected dataset: %+v", stats)
+	case stats.DatasetPercentage != 74.939796447753906 || stats.AllocatorFragmentationRatio != 1.2462643384933472 || stats.Fragmentation != 5.0149283409118652:
+		t.Fatalf("unexpected ratios: %+v", stats)
+	}
+	if len(stats.Databases) != 2 || *stats.Databases[0] != (server.DBMemoryOverhead{HashTableMain: 72, HashTableExpires: 32}) ||
+		stats.Databases[9].HashTableMain != 120 {
+		t.Fatalf("unexpected databases: %+v", stats.Databases)
+	}
+	if !reflect.DeepEqual(stats.Others, map[string]string{"overhead.db.hashtable.lut": "16"}) {
+		t.Fatalf("unexpected others: %+v", stats.Others)
+	}
+
+	if _, err = readTestReply(t, []interface{}{"db.x", []interface{}{}}).parseMemoryStats(); err == nil {
+		t.Fatalf("expect error for invalid database")
+	}
+}
+
+func TestParseSentinel(t *testing.T) {
+	// SENTINEL MASTER mymaster, 省略了部分字段
+	masters, err := readTestReply(t, []interface{}{[]interface{}{
+		"name", "mymaster", "ip", "127.0.0.1", "port", "6379", "runid", "953ae6a589449c13ddefaee3538d356d287f509b",
+		"flags", "master,disconnected", "link-pending-commands", "0", "link-refcount", "1",
+		"last-ping-sent", "0", "last-ok-ping-reply", "735", "last-ping-reply", "735", "down-after-milliseconds", "5000",
+		"info-refresh", "126", "role-reported", "master", "role-reported-time", "532439", "config-epoch", "1",
+		"num-slaves", "1", "num-other-sentinels", "2", "quorum", "2", "failover-timeout", "60000", "parallel-syncs", "1",
+	}}).parseSentinelMasters()
+	if err != nil {
+		t.Fatalf("parse masters err: %v", err)
+	}
+	m := masters[0]
+	if len(masters) != 1 || m.Name != "mymaster" || m.IP != "127.0.0.1" || m.Port != "6379" || !m.HasFlag("disconnected") {
+		t.Fatalf("unexpected master: %+v", m)
+	}
+	if m.LastOkPingReply != 735 || m.DownAfterMilliseconds != 5000 || m.RoleReportedTime != 532439 || m.NumOtherSentinels != 2 ||
+		m.Quorum != 2 || m.FailoverTimeout != 60000 || m.Fields["runid"] != m.RunID {
+		t.Fatalf("unexpected master: %+v", m)
+	}
+
+	// 旧版本没有replica-announced字段, 不存在的字段保持为0
+	replicas, err := readTestReply(t, []interface{}{[]interface{}{
+		"name", "127.0.0.1:6380", "ip", "127.0.0.1", "port", "6380", "flags", "slave",
+		"master-link-down-time", "0", "master-link-status", "ok", "master-host", "127.0.0.1", "master-port", "6379",
+		"slave-priority", "100", "slave-repl-offset", "118712",
+	}}).parseSentinelReplicas()
+	if err != nil {
+		t.Fatalf("parse replicas err: %v", err)
+	}
+	r := replicas[0]
+	if len(replicas) != 1 || r.Name != "127.0.0.1:6380" || r.MasterLinkStatus != "ok" || r.MasterPort != "6379" ||
+		r.SlavePriority != 100 || r.SlaveReplOffset != 118712 || r.ReplicaAnnounced != 0 {
+		t.Fatalf("unexpected replica: %+v", r)
+	}
+
+	sentinels, err := readTestReply(t, []interface{}{[]interface{}{
+		"name", "c0d4ae6a52a0a0e1b2a1a0b6bd2f33b2b7a2a2c1", "ip", "127.0.0.1", "port", "26380", "flags", "sentinel",
+		"last-hello-message", "312", "voted-leader", "?", "voted-leader-epoch", "0",
+	}}).parseSentinelSentinels()
+	if err != nil {
+		t.Fatalf("parse sentinels err: %v", err)
+	}
+	if s := sentinels[0]; len(sentinels) != 1 || s.Port != "26380" || s.LastHelloMessage != 312 || s.VotedLeader != "?" {
+		t.Fatalf("unexpected sentinel: %+v", s)
+	}
+
+	// 数值字段不是整数时返回错误
+	if _, err = readTestReply(t, []interface{}{"name", "mymaster", "quorum", "two"}).parseSentinelMaster(); err == nil {
+		t.Fatalf("expect error for invalid quorum")
+	}
+	if _, err = readTestReply(t, []interface{}{[]interface{}{"name", "x", "slave-priority", ""}}).parseSentinelReplicas(); err == nil {
+		t.Fatalf("expect error for invalid slave-priority")
+	}
+}
+
+func TestParseSentinelInfoCache(t *testing.T) {
+	caches, err := readTestReply(t, []interface{}{
+		"mymaster", []interface{}{
+			[]interface{}{1500, "# Server\r\nredis_version:7.2.4\r\n"},
+			[]interface{}{2000, nil},
+		},
+		"other", []interface{}{},
+	}).parseSentinelInfoCache()
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	want := []*sentinel.InfoCache{
+		{Name: "mymaster", Entries: []sentinel.InfoCacheEntry{{Age: 1500, Info: "# Server\r\nredis_version:7.2.4\r\n"}, {Age: 2000}}},
+		{Name: "other"},
+	}
+	if !reflect.DeepEqual(caches, want) {
+		t.Fatalf("unexpected info cache: %+v", caches)
+	}
+
+	// 末尾没有配对的主节点名称不能被静默丢弃
+	if _, err = readTestReply(t, []interface{}{"mymaster", []interface{}{}, "other"}).parseSentinelInfoCache(); err == nil {
+		t.Fatalf("expect error for unpaired element")
+	}
+	if _, err = readTestReply(t, []interface{}{"mymaster", []interface{}{[]interface{}{"soon", nil}}}).parseSentinelInfoCache(); err == nil {
+		t.Fatalf("expect error for invalid age")
+	}
+}
@@ -0,0 +1,165 @@
+package rediss
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pyihe/rediss/pool"
+)
+
+// RetryPolicy 命令级别的重试策略
+// 只有发生网络错误或者服务器返回暂时性错误(LOADING、BUSY、TRYAGAIN、CLUSTERDOWN、MASTERDOWN、READONLY)时才会重试,
+// 并且默认只重试幂等的命令, 非幂等的命令(如INCR、LPUSH)在网络错误时无法确定是否已经被执行, 重试可能导致重复执行
+type RetryPolicy struct {
+	MaxRetries     int           // 最大重试次数, 为0时不重试
+	MinBackoff     time.Duration // 第一次重试前的等待时长, 之后每次翻倍
+	MaxBackoff     time.Duration // 单次等待时长的上限
+	MaxElapsedTime time.Duration // 从第一次执行开始计算的最长时间, 超过后不再重试, 为0时不限制
+	RetryUnsafe    bool          // 是否重试非幂等的命令
+}
+
+// DefaultRetryPolicy 默认的重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MinBackoff: 8 * time.Millisecond,
+	MaxBackoff: 512 * time.Millisecond,
+}
+
+// 第attempt次重试前的等待时长: 指数退避, 并在[d/2, d)之间随机, 避免大量客户端同时重试
+func (rp *RetryPolicy) backoff(attempt int) time.Duration {
+	d := rp.MinBackoff
+	for i := 0; i < attempt && d < rp.MaxBackoff; i++ {
+		d *= 2
+	}
+	if rp.MaxBackoff > 0 && d > rp.MaxBackoff {
+		d = rp.MaxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// 服务器返回的可以重试的暂时性错误的前缀
+var retryableErrorPrefixes = []string{
+	"LOADING",     // 服务器正在加载数据
+	"BUSY",        // 脚本或者函数正在执行
+	"TRYAGAIN",    // 集群中多key命令的key正在迁移
+	"CLUSTERDOWN", // 集群不可用
+	"MASTERDOWN",  // 副本与主节点断开且不允许读取旧数据
+	"READONLY",    // 故障转移后连接到了副本
+}
+
+// 判断错误是否可以重试
+func isRetryableError(err error) bool {
+	switch err {
+	case nil, NilReply, pool.ErrPoolTimeout, pool.ErrAlreadyClosedPool, pool.ErrUninitializedPool:
+		return false
+	case io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+	// 超时的命令可能仍在执行, 重试只会增加服务器的负担
+	if e, ok := err.(net.Error); ok {
+		return !e.Timeout()
+	}
+	msg := err.Error()
+	for _, prefix := range retryableErrorPrefixes {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 幂等的命令, 重复执行不会改变结果以及返回值, 包括只读命令以及设置为固定值的写命令
+// 带有NX语义的命令(SETNX、HSETNX等)以及返回受影响数量或者旧值的写命令(DEL、SADD、ZADD、SETBIT等)重复执行时返回值会改变, 所以不包括在内
+var idempotentCommands = map[string]bool{
+	// 只读命令
+	"bitcount": true, "bitfield_ro": true, "bitpos": true, "dbsize": true, "dump": true, "echo": true,
+	"exists": true, "expiretime": true, "geodist": true, "geohash": true, "geopos": true,
+	"georadius_ro": true, "georadiusbymember_ro": true, "geosearch": true, "get": true, "getbit": true,
+	"getrange": true, "hexists": true, "hget": true, "hgetall": true, "hkeys": true, "hlen": true,
+	"hmget": true, "hrandfield": true, "hscan": true, "hstrlen": true, "hvals": true, "info": true,
+	"keys": true, "lcs": true, "lindex": true, "llen": true, "lpos": true, "lrange": true, "mget": true,
+	"pexpiretime": true, "pfcount": true, "ping": true, "pttl": true, "randomkey": true, "scan": true,
+	"scard": true, "sdiff": true, "sinter": true, "sintercard": true, "sismember": true, "smembers": true,
+	"smismember": true, "srandmember": true, "sscan": true, "strlen": true, "substr": true, "sunion": true,
+	"time": true, "touch": true, "ttl": true, "type": true, "xlen": true, "xrange": true, "xrevrange": true,
+	"zcard": true, "zcount": true, "zdiff": true, "zinter": true, "zintercard": true, "zlexcount": true,
+	"zmscore": true, "zrandmember": true, "zrange": true, "zrangebylex": true, "zrangebyscore": true,
+	"zrank": true, "zrevrange": true, "zrevrangebylex": true, "zrevrangebyscore": true, "zrevrank": true,
+	"zscan": true, "zscore": true, "zunion": true,
+	// 幂等的写命令, 其中SET、EXPIRE等命令带有条件或者返回旧值的可选项时不幂等, 见unsafeOptions
+	"expire": true, "expireat": true, "hmset": true, "mset": true, "pexpire": true, "pexpireat": true,
+	"psetex": true, "set": true, "setex": true, "setrange": true,
+}
+
+// 使幂等的命令变为不幂等的可选项, 以及可选项开始的位置(命令名称的位置为0):
+// SET key value [NX | XX] [GET] ...: NX、XX重复执行时返回nil, GET重复执行时返回的是第一次写入的值
+// EXPIRE key seconds [NX | XX | GT | LT]: 条件满足与否在重复执行时可能改变
+var unsafeOptions = map[string]struct {
+	from    int
+	options map[string]bool
+}{
+	"set":       {3, map[string]bool{"NX": true, "XX": true, "GET": true}},
+	"expire":    {3, map[string]bool{"NX": true, "XX": true, "GT": true, "LT": true}},
+	"expireat":  {3, map[string]bool{"NX": true, "XX": true, "GT": true, "LT": true}},
+	"pexpire":   {3, map[string]bool{"NX": true, "XX": true, "GT": true, "LT": true}},
+	"pexpireat": {3, map[string]bool{"NX": true, "XX": true, "GT": true, "LT": true}},
+}
+
+// 判断命令是否可以安全的重试, 不在列表中的命令如果已经通过RefreshCommands缓存了元数据, 只读命令同样可以重试
+func (c *Client) isIdempotent(cmd []byte) bool {
+	name := commandName(cmd)
+	if idempotentCommands[name] {
+		rule, ok := unsafeOptions[name]
+		if !ok {
+			return true
+		}
+		argv := decodeCommand(cmd)
+		for i := rule.from; i < len(argv); i++ {
+			if rule.options[strings.ToUpper(argv[i])] {
+				return false
+			}
+		}
+		return true
+	}
+	info, loaded := c.commands.get(name)
+	return loaded && info != nil && info.ReadOnly()
+}
+
+// 按照重试策略执行send, cmd为完整的命令, 调用的上下文取消后不再重试
+func (c *Client) withRetry(cmd []byte, send func() (*Reply, error)) (reply *Reply, err error) {
+	policy := c.retryPolicy
+	if c.noRetry || policy == nil || policy.MaxRetries <= 0 {
+		return send()
+	}
+
+	var (
+		ctx   = c.context()
+		start = time.Now()
+	)
+	for attempt := 0; ; attempt++ {
+		reply, err = send()
+		if attempt >= policy.MaxRetries || !isRetryableError(err) {
+			return
+		}
+		if !policy.RetryUnsafe && !c.retryUnsafe && !c.isIdempotent(cmd) {
+			return
+		}
+		delay := policy.backoff(attempt)
+		if policy.MaxElapsedTime > 0 && time.Since(start)+delay > policy.MaxElapsedTime {
+			return
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
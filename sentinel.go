@@ -0,0 +1,44 @@
+package rediss
+
+// 哨兵的默认地址
+const defaultSentinelAddress = "127.0.0.1:26379"
+
+// SentinelClient 哨兵客户端, 用于直接向哨兵发送SENTINEL系列的管理命令
+// 与Client共用连接池以及回复的解析逻辑, 支持除WithDatabase外的所有Option
+type SentinelClient struct {
+	client *Client
+}
+
+func NewSentinelClient(opts ...Option) (*SentinelClient, error) {
+	options := make([]Option, 0, len(opts)+2)
+	options = append(options, WithAddress(defaultSentinelAddress))
+	options = append(options, opts...)
+	options = append(options, withSentinel())
+	client, err := New(options...)
+	if err != nil {
+		return nil, err
+	}
+	return &SentinelClient{client: client}, nil
+}
+
+// 标记客户端连接的是哨兵
+func withSentinel() Option {
+	return func(client *Client) {
+		client.sentinel = true
+		client.database = new(int32)
+	}
+}
+
+func (s *SentinelClient) Close() {
+	s.client.Close()
+}
+
+// DoCommand 向哨兵发送任意命令
+func (s *SentinelClient) DoCommand(cmds ...interface{}) (*Reply, error) {
+	return s.client.DoCommand(cmds...)
+}
+
+// Ping 检查哨兵是否可用
+func (s *SentinelClient) Ping() error {
+	return s.client.Ping()
+}
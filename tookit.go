@@ -1,17 +1,87 @@
 package rediss
 
 import (
+	"bytes"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	innerBytes "github.com/pyihe/go-pkg/bytes"
 	"github.com/pyihe/go-pkg/errors"
 	"github.com/pyihe/rediss/args"
+	"github.com/pyihe/rediss/model/server"
+	"github.com/pyihe/rediss/pool"
 )
 
-func assertDatabase(db int32) {
-	if db < 0 || db > 15 {
-		panic("invalid database")
+// 判断服务器返回的错误是否为命令不存在, 用于兼容不支持某些命令的旧版本服务器
+func isUnknownCommand(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "ERR unknown command")
+}
+
+// 从编码后的命令中解析出命令名称(小写), 命令的格式为: *N\r\n$len\r\nNAME\r\n...
+func commandName(cmd []byte) string {
+	var start int
+	for i := 0; i < 2; i++ {
+		n := bytes.IndexByte(cmd[start:], '\n')
+		if n < 0 {
+			return ""
+		}
+		start += n + 1
+	}
+	end := bytes.IndexByte(cmd[start:], '\r')
+	if end < 0 {
+		return ""
+	}
+	return strings.ToLower(string(cmd[start : start+end]))
+}
+
+// 是否为SELECT命令, 用于在调用方直接发送SELECT后同步连接的数据库, 避免每个命令都解析命令名称
+func isSelectCommand(cmd []byte) bool {
+	const prefix = "*2\r\n$6\r\n"
+	return len(cmd) >= len(prefix)+6 && string(cmd[:len(prefix)]) == prefix && strings.EqualFold(string(cmd[len(prefix):len(prefix)+6]), "SELECT")
+}
+
+// 调用方直接发送SELECT成功(回复OK)后, 记录连接当前的数据库;
+// 事务中的SELECT回复QUEUED, 是否执行取决于EXEC还是DISCARD, 此时将连接的数据库标记为未知, 下次使用前重新SELECT;
+// 固定的连接同时记录为Conn的数据库, 之后Conn上的命令不会再切换回原来的数据库
+func (c *Client) trackSelect(conn *pool.RedisConn, cmd []byte, reply *Reply) {
+	if !isSelectCommand(cmd) || reply == nil {
+		return
+	}
+	if reply.ValueString() != "OK" {
+		conn.SetDB(-1)
+		return
+	}
+	if argv := decodeCommand(cmd); len(argv) == 2 {
+		if db, err := strconv.Atoi(argv[1]); err == nil {
+			conn.SetDB(db)
+			if c.pinned != nil {
+				atomic.StoreInt32(&c.pinned.db, int32(db))
+			}
+		}
+	}
+}
+
+// 阻塞命令的读超时: 命令的阻塞时长加上余量, 阻塞时长为0(永久阻塞)时不设置读超时
+func blockingReadTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return 0
+	}
+	return timeout + blockingReadMargin
+}
+
+// 将以秒为单位的浮点数转换为time.Duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// 数据库的数量由服务器的databases配置决定(默认为16), 这里只检查负数, 超出范围时SELECT将返回错误
+func checkDatabase(db int32) error {
+	if db < 0 {
+		return ErrInvalidDatabase
 	}
+	return nil
 }
 
 func appendArgs(args *args.Args, arg interface{}) (err error) {
@@ -49,3 +119,99 @@ func dataLen(b []byte) (int, error) {
 
 	return strconv.Atoi(innerBytes.String(b))
 }
+
+// 解析MONITOR命令推送的记录, 格式如下:
+// 1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+// 1339518087.877697 [0 lua] "set" "foo" "bar"
+// 1339518083.107412 [0 [::1]:60866] "ping", IPv6地址中也包含方括号, 所以以`] "`作为客户端信息的结尾
+func parseMonitorLine(s string) (line *server.MonitorLine, err error) {
+	sp := strings.IndexByte(s, ' ')
+	lb := strings.IndexByte(s, '[')
+	rb := strings.Index(s, `] "`)
+	if sp < 0 || lb < sp || rb < lb {
+		return nil, errors.New("invalid monitor line")
+	}
+	line = &server.MonitorLine{}
+
+	// 时间戳, 单位为秒, 精确到微秒
+	secs, usecs := s[:sp], "0"
+	if dot := strings.IndexByte(secs, '.'); dot >= 0 {
+		secs, usecs = secs[:dot], secs[dot+1:]
+	}
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	usec, err := strconv.ParseInt(usecs, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	line.Time = time.Unix(sec, usec*int64(time.Microsecond))
+
+	// 数据库索引和客户端地址
+	client := s[lb+1 : rb]
+	if sp = strings.IndexByte(client, ' '); sp < 0 {
+		return nil, errors.New("invalid monitor line")
+	}
+	if line.DB, err = strconv.ParseInt(client[:sp], 10, 64); err != nil {
+		return nil, err
+	}
+	line.Addr = client[sp+1:]
+
+	// 命令及参数, 每个参数都被双引号包裹
+	line.Args, err = unquoteMonitorArgs(s[rb+1:])
+	return
+}
+
+// 还原MONITOR中被转义的参数, 转义规则与Redis的sdscatrepr一致:
+// \\, \", \n, \r, \t, \a, \b以及不可打印字符的\xHH
+func unquoteMonitorArgs(s string) (result []string, err error) {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' {
+			continue
+		}
+		if s[i] != '"' {
+			return nil, errors.New("invalid monitor argument")
+		}
+		buf = buf[:0]
+		for i++; i < len(s) && s[i] != '"'; i++ {
+			if s[i] != '\\' {
+				buf = append(buf, s[i])
+				continue
+			}
+			if i++; i >= len(s) {
+				return nil, errors.New("invalid monitor argument")
+			}
+			switch s[i] {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'a':
+				buf = append(buf, '\a')
+			case 'b':
+				buf = append(buf, '\b')
+			case 'x':
+				if i+2 >= len(s) {
+					return nil, errors.New("invalid monitor argument")
+				}
+				b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+				if err != nil {
+					return nil, err
+				}
+				buf = append(buf, byte(b))
+				i += 2
+			default:
+				buf = append(buf, s[i])
+			}
+		}
+		if i >= len(s) {
+			return nil, errors.New("invalid monitor argument")
+		}
+		result = append(result, string(buf))
+	}
+	return
+}
@@ -0,0 +1,43 @@
+package rediss
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMonitorLine(t *testing.T) {
+	line, err := parseMonitorLine(`1339518083.107412 [3 127.0.0.1:60866] "set" "k\"1" "a\\b\r\n\x00\xe4"`)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if line.Time.Unix() != 1339518083 || line.Time.Nanosecond() != 107412000 {
+		t.Fatalf("unexpected time: %v", line.Time)
+	}
+	if line.DB != 3 || line.Addr != "127.0.0.1:60866" {
+		t.Fatalf("unexpected client: %d %s", line.DB, line.Addr)
+	}
+	want := []string{"set", `k"1`, "a\\b\r\n\x00\xe4"}
+	if !reflect.DeepEqual(line.Args, want) {
+		t.Fatalf("unexpected args: %q", line.Args)
+	}
+
+	line, err = parseMonitorLine(`1339518087.877697 [0 lua] "get" ""`)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if line.Addr != "lua" || !reflect.DeepEqual(line.Args, []string{"get", ""}) {
+		t.Fatalf("unexpected line: %+v", line)
+	}
+
+	line, err = parseMonitorLine(`1339518087.877697 [1 [::1]:60866] "get" "]"`)
+	if err != nil {
+		t.Fatalf("parse err: %v", err)
+	}
+	if line.DB != 1 || line.Addr != "[::1]:60866" || !reflect.DeepEqual(line.Args, []string{"get", "]"}) {
+		t.Fatalf("unexpected line: %+v", line)
+	}
+
+	if _, err = parseMonitorLine(`1339518087.877697 [0 lua] "get`); err == nil {
+		t.Fatalf("expect err for unterminated argument")
+	}
+}
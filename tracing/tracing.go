@@ -0,0 +1,180 @@
+// Package tracing 提供链路追踪的Hook, 为每个命令或者管道创建一个span
+// 通过最小化的Tracer接口与具体的链路追踪实现解耦, 可以适配OpenTelemetry等实现
+package tracing
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pyihe/rediss"
+)
+
+// span的属性名称, 遵循OpenTelemetry的数据库语义约定
+const (
+	AttrDBSystem    = "db.system"
+	AttrDBStatement = "db.statement"
+	AttrDBIndex     = "db.redis.database_index"
+	AttrNumCmd      = "db.redis.num_cmd"
+	AttrPeerName    = "net.peer.name"
+	AttrPeerPort    = "net.peer.port"
+
+	AttrBreakerFrom = "rediss.circuit_breaker.from"
+	AttrBreakerTo   = "rediss.circuit_breaker.to"
+)
+
+// Attribute span的属性
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span 链路追踪的span
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer 创建span, ctx中如果已经有span, 新的span应作为它的子span
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type Option func(h *Hook)
+
+// WithRedaction 隐藏命令的参数, db.statement中只保留命令名称, 参数以?代替, 避免记录敏感数据;
+// 不开启时AUTH、HELLO、MIGRATE等命令中的密码同样会被隐藏
+func WithRedaction() Option {
+	return func(h *Hook) {
+		h.redact = true
+	}
+}
+
+// WithMaxStatementLen 限制db.statement的最大长度, 超出的部分将被截断, 默认不限制
+func WithMaxStatementLen(n int) Option {
+	return func(h *Hook) {
+		h.maxStatementLen = n
+	}
+}
+
+// Hook 链路追踪的Hook, 通过rediss.WithHooks或者Client.AddHook添加
+// 需要通过Client.With(rediss.CallContext(ctx))传入上下文, span才能关联到调用方的链路中
+type Hook struct {
+	rediss.BaseHook
+
+	tracer          Tracer
+	redact          bool
+	maxStatementLen int
+}
+
+func New(tracer Tracer, opts ...Option) *Hook {
+	h := &Hook{tracer: tracer}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type spanKey struct{}
+
+func (h *Hook) BeforeProcess(ctx context.Context, cmd *rediss.Cmd) (context.Context, error) {
+	ctx, span := h.tracer.Start(ctx, cmd.Name)
+	span.SetAttributes(h.attributes(cmd)...)
+	span.SetAttributes(Attribute{Key: AttrDBStatement, Value: h.statement(cmd)})
+	return context.WithValue(ctx, spanKey{}, span), nil
+}
+
+func (h *Hook) AfterProcess(ctx context.Context, cmd *rediss.Cmd) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	if cmd.Err != nil && cmd.Err != rediss.NilReply {
+		span.RecordError(cmd.Err)
+	}
+	span.End()
+}
+
+func (h *Hook) BeforeProcessPipeline(ctx context.Context, cmds []*rediss.Cmd) (context.Context, error) {
+	ctx, span := h.tracer.Start(ctx, "pipeline")
+	if len(cmds) > 0 {
+		span.SetAttributes(h.attributes(cmds[0])...)
+	}
+	statements := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		statements = append(statements, h.statement(cmd))
+	}
+	span.SetAttributes(
+		Attribute{Key: AttrDBStatement, Value: h.truncate(strings.Join(statements, "\n"))},
+		Attribute{Key: AttrNumCmd, Value: len(cmds)},
+	)
+	return context.WithValue(ctx, spanKey{}, span), nil
+}
+
+func (h *Hook) AfterProcessPipeline(ctx context.Context, cmds []*rediss.Cmd) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	// 只记录第一个错误
+	for _, cmd := range cmds {
+		if cmd.Err != nil && cmd.Err != rediss.NilReply {
+			span.RecordError(cmd.Err)
+			break
+		}
+	}
+	span.End()
+}
+
+// BreakerStateChanged 为熔断器的每次状态变化创建一个独立的span, 状态变化不属于任何调用方的链路
+func (h *Hook) BreakerStateChanged(from, to rediss.BreakerState) {
+	_, span := h.tracer.Start(context.Background(), "circuit_breaker")
+	span.SetAttributes(
+		Attribute{Key: AttrDBSystem, Value: "redis"},
+		Attribute{Key: AttrBreakerFrom, Value: from.String()},
+		Attribute{Key: AttrBreakerTo, Value: to.String()},
+	)
+	span.End()
+}
+
+// 命令公共的属性
+func (h *Hook) attributes(cmd *rediss.Cmd) []Attribute {
+	attrs := []Attribute{
+		{Key: AttrDBSystem, Value: "redis"},
+		{Key: AttrDBIndex, Value: cmd.DB},
+	}
+	host, port, err := net.SplitHostPort(cmd.Addr)
+	if err != nil {
+		return append(attrs, Attribute{Key: AttrPeerName, Value: cmd.Addr})
+	}
+	attrs = append(attrs, Attribute{Key: AttrPeerName, Value: host})
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, Attribute{Key: AttrPeerPort, Value: p})
+	}
+	return attrs
+}
+
+// 生成db.statement, 开启隐藏参数时只保留命令名称, 否则只隐藏命令中的凭证
+func (h *Hook) statement(cmd *rediss.Cmd) string {
+	if len(cmd.Args) == 0 {
+		return ""
+	}
+	if !h.redact {
+		return h.truncate(strings.Join(cmd.RedactedArgs(), " "))
+	}
+	var b strings.Builder
+	b.WriteString(cmd.Args[0])
+	for range cmd.Args[1:] {
+		b.WriteString(" " + rediss.RedactedArg)
+	}
+	return h.truncate(b.String())
+}
+
+func (h *Hook) truncate(s string) string {
+	if h.maxStatementLen > 0 && len(s) > h.maxStatementLen {
+		return s[:h.maxStatementLen]
+	}
+	return s
+}
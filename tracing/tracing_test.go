@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pyihe/rediss"
+)
+
+// 内存中的Tracer, 记录所有的span
+type recorder struct {
+	spans []*recordSpan
+}
+
+type recordSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (r *recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &recordSpan{name: name, attrs: make(map[string]interface{})}
+	r.spans = append(r.spans, span)
+	return ctx, span
+}
+
+func (s *recordSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordSpan) End() {
+	s.ended = true
+}
+
+func TestHook(t *testing.T) {
+	r := &recorder{}
+	h := New(r)
+
+	cmd := &rediss.Cmd{Name: "set", Args: []string{"SET", "key", "secret"}, Addr: "127.0.0.1:6379", DB: 3}
+	ctx, _ := h.BeforeProcess(context.Background(), cmd)
+	cmd.Err = errors.New("ERR failed")
+	h.AfterProcess(ctx, cmd)
+
+	if len(r.spans) != 1 {
+		t.Fatalf("expect 1 span, got %d", len(r.spans))
+	}
+	span := r.spans[0]
+	if span.name != "set" || !span.ended || span.err != cmd.Err {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+	expect := map[string]interface{}{
+		AttrDBSystem:    "redis",
+		AttrDBStatement: "SET key secret",
+		AttrDBIndex:     3,
+		AttrPeerName:    "127.0.0.1",
+		AttrPeerPort:    6379,
+	}
+	for k, v := range expect {
+		if span.attrs[k] != v {
+			t.Fatalf("attribute %s: expect %v, got %v", k, v, span.attrs[k])
+		}
+	}
+}
+
+func TestHookBreakerStateChanged(t *testing.T) {
+	r := &recorder{}
+	// 通过可选接口调用, 与熔断器通知Hook的方式一致
+	var h rediss.BreakerHook = New(r)
+	h.BreakerStateChanged(rediss.BreakerClosed, rediss.BreakerOpen)
+	if len(r.spans) != 1 || r.spans[0].name != "circuit_breaker" || !r.spans[0].ended {
+		t.Fatalf("unexpected spans: %+v", r.spans)
+	}
+	if r.spans[0].attrs[AttrBreakerFrom] != "closed" || r.spans[0].attrs[AttrBreakerTo] != "open" {
+		t.Fatalf("unexpected attributes: %v", r.spans[0].attrs)
+	}
+}
+
+func TestStatementRedactsCredentials(t *testing.T) {
+	h := New(&recorder{})
+	cmd := &rediss.Cmd{Name: "sentinel", Args: []string{"SENTINEL", "SET", "mymaster", "auth-pass", "secret"}}
+	if got := h.statement(cmd); got != "SENTINEL SET mymaster auth-pass ?" {
+		t.Fatalf("unexpected statement: %q", got)
+	}
+}
+
+func TestHookPipelineWithRedaction(t *testing.T) {
+	r := &recorder{}
+	h := New(r, WithRedaction())
+
+	cmds := []*rediss.Cmd{
+		{Name: "auth", Args: []string{"AUTH", "password"}, Addr: "localhost:6379"},
+		{Name: "get", Args: []string{"GET", "key"}, Addr: "localhost:6379", Err: rediss.NilReply},
+	}
+	ctx, _ := h.BeforeProcessPipeline(context.Background(), cmds)
+	h.AfterProcessPipeline(ctx, cmds)
+
+	span := r.spans[0]
+	if span.name != "pipeline" || !span.ended || span.err != nil {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+	if span.attrs[AttrDBStatement] != "AUTH ?\nGET ?" || span.attrs[AttrNumCmd] != 2 {
+		t.Fatalf("unexpected attributes: %v", span.attrs)
+	}
+}